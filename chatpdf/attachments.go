@@ -0,0 +1,84 @@
+package chatpdf
+
+import "os"
+
+// Attached image geometry
+const (
+	imageDPI       = 96.0  // pixel density images are shown at, unless that is too large
+	maxImageHeight = 120.0 // in mm; taller images are scaled down
+	imageGap       = 2.0   // in mm, above and below each image
+)
+
+// loadImage returns the attached image at path, loading it on first use.
+// ok is false when the image is missing or cannot be decoded; a warning is
+// given the first time.
+func (g *PDFGenerator) loadImage(path string) (img *loadedImage, ok bool) {
+	if img, seen := g.images[path]; seen {
+		return img, img != nil
+	}
+
+	w, h, err := g.backend.ImageSize(path)
+	switch {
+	case os.IsNotExist(err):
+		g.warn(Warning{Code: WarnImageMissing, Path: path, Err: err})
+	case err != nil:
+		g.warn(Warning{Code: WarnImageInvalid, Path: path, Err: err})
+	case w <= 0 || h <= 0:
+	default:
+		img = &loadedImage{width: w, height: h}
+	}
+	g.images[path] = img
+	return img, img != nil
+}
+
+// addAttachments draws the entry's attachments below the message: PNG, JPEG
+// and GIF images with a local copy are shown scaled to fit the column, on
+// the right of it when right is set, and other files are listed by name and
+// size
+func (g *PDFGenerator) addAttachments(entry ChatEntry, x, width float64, right bool) {
+	const fontSize = 9
+	for _, a := range entry.Attachments {
+		if a.Path != "" && imageFormat(a.Path) != "" {
+			if img, ok := g.loadImage(a.Path); ok {
+				g.addImage(a, img, x, width, right)
+				continue
+			}
+		}
+
+		text := "Attachment: " + a.Name
+		if a.Size > 0 {
+			text += " (" + formatSize(a.Size) + ")"
+		}
+		g.setFont("", fontSize)
+		g.setTextColor(g.theme.Meta)
+		g.drawLines(g.wrapRuns([]textRun{{text: text}}, width, fontSize), x, fontSize, 0, nil)
+	}
+}
+
+// addImage draws an attached image at its size at imageDPI, scaled down to
+// the column width and maxImageHeight, starting a new page when it does not
+// fit on this one. Images with a URL link to it.
+func (g *PDFGenerator) addImage(a Attachment, img *loadedImage, x, width float64, right bool) {
+	w := img.width * 25.4 / imageDPI
+	h := img.height * 25.4 / imageDPI
+	maxHeight := min(maxImageHeight, (g.contentBottom()-g.contentTop())*0.6)
+	if w > width {
+		w, h = width, h*width/w
+	}
+	if h > maxHeight {
+		w, h = w*maxHeight/h, maxHeight
+	}
+	if right {
+		x += width - w
+	}
+
+	g.y += imageGap
+	if g.y+h > g.contentBottom() {
+		g.newPage()
+	}
+	g.backend.Image(a.Path, x, g.y, w, h)
+	if a.URL != "" {
+		g.backend.Link(x, g.y, w, h, a.URL)
+	}
+	g.y += h + imageGap
+}
@@ -0,0 +1,49 @@
+package chatpdf
+
+import (
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAttachmentWarnings(t *testing.T) {
+	dir := t.TempDir()
+	shown := filepath.Join(dir, "shown.png")
+	f, err := os.Create(shown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.png")
+
+	g := NewPDFGenerator("Attachments")
+	before := len(g.Warnings())
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	g.AddChatEntry(ChatEntry{Timestamp: start, User: "alice", Message: "two pictures", Attachments: []Attachment{
+		{Name: "shown.png", Path: shown},
+		{Name: "missing.png", Path: missing, Size: 2048},
+	}})
+	g.AddChatEntry(ChatEntry{Timestamp: start.Add(time.Minute), User: "bob", Message: "again", Attachments: []Attachment{
+		{Name: "missing.png", Path: missing},
+	}})
+	if err := g.Output(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := g.Warnings()[before:]
+	if len(warnings) != 1 || warnings[0].Code != WarnImageMissing || warnings[0].Path != missing {
+		t.Errorf("warnings = %v, want one %s warning for %s", warnings, WarnImageMissing, missing)
+	}
+	if img := g.images[shown]; img == nil || img.width != 40 || img.height != 20 {
+		t.Errorf("shown image = %+v, want 40x20", img)
+	}
+}
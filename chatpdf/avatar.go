@@ -0,0 +1,110 @@
+package chatpdf
+
+import (
+	"hash/fnv"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Avatar column geometry in mm
+const (
+	avatarSize = 9.0
+	avatarGap  = 3.0
+)
+
+// avatarPalette holds the background colors for generated avatars
+var avatarPalette = []Color{
+	{229, 57, 53},
+	{216, 27, 96},
+	{142, 36, 170},
+	{57, 73, 171},
+	{30, 136, 229},
+	{0, 137, 123},
+	{67, 160, 71},
+	{244, 81, 30},
+	{109, 76, 65},
+	{84, 110, 122},
+}
+
+// loadedImage is the pixel size of an image loaded by the backend
+type loadedImage struct {
+	width, height float64
+}
+
+// loadAvatar returns the avatar icon at path, loading it on first use. Each
+// path is read at most once per document; ok is false when the image is
+// missing or cannot be decoded.
+func (g *PDFGenerator) loadAvatar(path string) (img *loadedImage, ok bool) {
+	if img, seen := g.avatars[path]; seen {
+		return img, img != nil
+	}
+
+	w, h, err := g.backend.ImageSize(path)
+	switch {
+	case os.IsNotExist(err):
+		g.warn(Warning{Code: WarnAvatarMissing, Path: path, Err: err})
+	case err != nil:
+		g.warn(Warning{Code: WarnAvatarInvalid, Path: path, Err: err})
+	default:
+		img = &loadedImage{width: w, height: h}
+	}
+	g.avatars[path] = img
+	return img, img != nil
+}
+
+// drawAvatar draws the entry's icon in a square at (x, y), or a generated
+// initials avatar when the entry has no usable icon
+func (g *PDFGenerator) drawAvatar(entry ChatEntry, x, y float64) {
+	if entry.IconPath != "" {
+		if img, ok := g.loadAvatar(entry.IconPath); ok {
+			// Fit the image into the square, keeping its aspect ratio
+			w, h := avatarSize, avatarSize
+			if img.width > img.height {
+				h = avatarSize * img.height / img.width
+			} else if img.height > img.width {
+				w = avatarSize * img.width / img.height
+			}
+			g.backend.Image(entry.IconPath, x+(avatarSize-w)/2, y+(avatarSize-h)/2, w, h)
+			return
+		}
+	}
+
+	r := avatarSize / 2
+	g.backend.SetFillColor(avatarColor(entry.User))
+	g.backend.Circle(x+r, y+r, r, "F")
+
+	const fontSize = 10
+	g.setFont("B", fontSize)
+	g.setTextColor(Color{255, 255, 255})
+	text := initials(entry.User)
+	w := g.stringWidth(text)
+	g.backend.Text(x+r-w/2, y+r+fontSize*ptToMM*0.35, text)
+}
+
+// avatarColor picks a stable palette color for a user name
+func avatarColor(user string) Color {
+	h := fnv.New32a()
+	h.Write([]byte(user))
+	return avatarPalette[h.Sum32()%uint32(len(avatarPalette))]
+}
+
+// initials returns up to two uppercase initials for a user name, or "?"
+func initials(user string) string {
+	var letters []rune
+	for _, word := range strings.Fields(user) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				letters = append(letters, unicode.ToUpper(r))
+				break
+			}
+		}
+		if len(letters) == 2 {
+			break
+		}
+	}
+	if len(letters) == 0 {
+		return "?"
+	}
+	return string(letters)
+}
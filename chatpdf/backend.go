@@ -0,0 +1,141 @@
+package chatpdf
+
+import (
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Backend is a drawing target for the document layout. Coordinates and
+// lengths are in millimetres from the top-left corner of the page; font
+// sizes are in points. Errors from drawing calls are kept by the backend
+// and reported by Err and Output.
+type Backend interface {
+	// PageSize returns the page width and height
+	PageSize() (width, height float64)
+	// AddPage starts a new page, running the page hooks
+	AddPage()
+	// PageNo returns the current 1-based page number
+	PageNo() int
+	// SetPageHooks sets functions that draw the header and footer of every page
+	SetPageHooks(header, footer func())
+	// TotalPagesAlias returns a placeholder that is replaced by the page
+	// count when the document is written, or "" when unsupported
+	TotalPagesAlias() string
+
+	// AddFont registers a UTF-8 TrueType font file under a family and a
+	// style of "", "B", "I" or "BI"
+	AddFont(family, style, path string) error
+	// SetFont selects a registered font; an empty family selects the
+	// backend's built-in font, if it has one
+	SetFont(family, style string, size float64)
+	// SetTextColor sets the color used by Text
+	SetTextColor(c Color)
+	// StringWidth measures s in the current font
+	StringWidth(s string) float64
+	// Text draws s with its baseline starting at (x, y)
+	Text(x, y float64, s string)
+
+	// SetFillColor sets the color used by filled shapes
+	SetFillColor(c Color)
+	// SetDrawColor sets the color used by lines and outlines
+	SetDrawColor(c Color)
+	// SetLineWidth sets the width of lines and outlines
+	SetLineWidth(width float64)
+	// Line draws a straight line
+	Line(x1, y1, x2, y2 float64)
+	// Rect draws a rectangle; style is "F" to fill, "D" to outline or "FD" for both
+	Rect(x, y, w, h float64, style string)
+	// RoundedRect draws a rectangle with corners of radius r
+	RoundedRect(x, y, w, h, r float64, style string)
+	// Circle draws a circle centered on (x, y)
+	Circle(x, y, r float64, style string)
+	// ImageSize loads a PNG, JPEG or GIF image and returns its pixel size
+	ImageSize(path string) (width, height float64, err error)
+	// Image draws an image scaled into the given box
+	Image(path string, x, y, w, h float64) error
+	// Link makes an area of the current page link to url
+	Link(x, y, w, h float64, url string)
+
+	// Err returns the first error recorded by a drawing call
+	Err() error
+	// Output writes the finished document to w
+	Output(w io.Writer) error
+}
+
+// backends lists the available backends by name
+var backends = map[string]func(pageSize string) (Backend, error){
+	"gofpdf": newGofpdfBackend,
+	"gopdf":  newGopdfBackend,
+}
+
+// BackendNames returns the names of the available backends in sorted order
+func BackendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newBackend creates the named backend, defaulting to gofpdf
+func newBackend(name, pageSize string) (Backend, error) {
+	if name == "" {
+		name = "gofpdf"
+	}
+	create, ok := backends[strings.ToLower(name)]
+	if !ok {
+		return nil, &OptionError{Option: "Backend", Value: name, Valid: BackendNames()}
+	}
+	return create(pageSize)
+}
+
+// pageCounter wraps a Backend to lay a document out without drawing it, so
+// the page count is known before the real pass. Fonts and measurements go
+// to the wrapped backend; everything that would draw is dropped.
+type pageCounter struct {
+	Backend
+	pages int
+}
+
+func (c *pageCounter) AddPage()                                        { c.pages++ }
+func (c *pageCounter) PageNo() int                                     { return c.pages }
+func (c *pageCounter) SetPageHooks(header, footer func())              {}
+func (c *pageCounter) SetTextColor(Color)                              {}
+func (c *pageCounter) Text(x, y float64, s string)                     {}
+func (c *pageCounter) SetFillColor(Color)                              {}
+func (c *pageCounter) SetDrawColor(Color)                              {}
+func (c *pageCounter) SetLineWidth(float64)                            {}
+func (c *pageCounter) Line(x1, y1, x2, y2 float64)                     {}
+func (c *pageCounter) Rect(x, y, w, h float64, style string)           {}
+func (c *pageCounter) RoundedRect(x, y, w, h, r float64, style string) {}
+func (c *pageCounter) Circle(x, y, r float64, style string)            {}
+func (c *pageCounter) Image(path string, x, y, w, h float64) error     { return nil }
+func (c *pageCounter) Link(x, y, w, h float64, url string)             {}
+
+// imageFormat returns the format of an image file, "png", "jpeg" or "gif",
+// from its extension or else its contents; "" when it is not a known image
+func imageFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return "png"
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".gif":
+		return "gif"
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	_, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return ""
+	}
+	return format
+}
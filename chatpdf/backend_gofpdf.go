@@ -0,0 +1,190 @@
+package chatpdf
+
+import (
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// gofpdfBackend draws with github.com/jung-kurt/gofpdf
+type gofpdfBackend struct {
+	pdf       *gofpdf.Fpdf
+	translate func(string) string
+	utf8      func(string) string
+	cp1252    func(string) string
+}
+
+// newGofpdfBackend creates a gofpdf document with pages of the given size
+func newGofpdfBackend(pageSize string) (Backend, error) {
+	pdf := gofpdf.New("P", "mm", pageSize, "")
+	// Pagination is handled by the layout code
+	pdf.SetAutoPageBreak(false, 0)
+	// Must precede font registration so the digits are kept in font subsets
+	pdf.AliasNbPages("{nb}")
+	if err := pdf.Error(); err != nil {
+		return nil, err
+	}
+
+	return &gofpdfBackend{
+		pdf:       pdf,
+		translate: bmpOnly,
+		utf8:      bmpOnly,
+		cp1252:    pdf.UnicodeTranslatorFromDescriptor(""),
+	}, nil
+}
+
+// bmpOnly replaces characters outside the Basic Multilingual Plane, such as
+// most emoji, which gofpdf's UTF-8 font subsetting cannot handle
+func bmpOnly(s string) string {
+	for _, r := range s {
+		if r > 0xFFFF {
+			return strings.Map(func(r rune) rune {
+				if r > 0xFFFF {
+					return unicode.ReplacementChar
+				}
+				return r
+			}, s)
+		}
+	}
+	return s
+}
+
+func (b *gofpdfBackend) PageSize() (float64, float64) {
+	return b.pdf.GetPageSize()
+}
+
+func (b *gofpdfBackend) AddPage() {
+	b.pdf.AddPage()
+}
+
+func (b *gofpdfBackend) PageNo() int {
+	return b.pdf.PageNo()
+}
+
+func (b *gofpdfBackend) SetPageHooks(header, footer func()) {
+	b.pdf.SetHeaderFunc(header)
+	b.pdf.SetFooterFunc(footer)
+}
+
+func (b *gofpdfBackend) TotalPagesAlias() string {
+	return "{nb}"
+}
+
+func (b *gofpdfBackend) AddFont(family, style, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	b.pdf.AddUTF8FontFromBytes(family, style, data)
+	if err := b.pdf.Error(); err != nil {
+		b.pdf.ClearError()
+		return err
+	}
+	return nil
+}
+
+func (b *gofpdfBackend) SetFont(family, style string, size float64) {
+	// The core Arial font only covers cp1252, so translate text for it
+	if family == "" {
+		family = "Arial"
+		b.translate = b.cp1252
+	} else {
+		b.translate = b.utf8
+	}
+	b.pdf.SetFont(family, style, size)
+}
+
+func (b *gofpdfBackend) SetTextColor(c Color) {
+	b.pdf.SetTextColor(c.R, c.G, c.B)
+}
+
+func (b *gofpdfBackend) StringWidth(s string) float64 {
+	return b.pdf.GetStringWidth(b.translate(s))
+}
+
+func (b *gofpdfBackend) Text(x, y float64, s string) {
+	b.pdf.Text(x, y, b.translate(s))
+}
+
+func (b *gofpdfBackend) SetFillColor(c Color) {
+	b.pdf.SetFillColor(c.R, c.G, c.B)
+}
+
+func (b *gofpdfBackend) SetDrawColor(c Color) {
+	b.pdf.SetDrawColor(c.R, c.G, c.B)
+}
+
+func (b *gofpdfBackend) SetLineWidth(width float64) {
+	b.pdf.SetLineWidth(width)
+}
+
+func (b *gofpdfBackend) Line(x1, y1, x2, y2 float64) {
+	b.pdf.Line(x1, y1, x2, y2)
+}
+
+func (b *gofpdfBackend) Rect(x, y, w, h float64, style string) {
+	b.pdf.Rect(x, y, w, h, style)
+}
+
+// RoundedRect builds the path by hand because gofpdf's RoundedRect leaves an
+// unbalanced graphics state save in the page stream
+func (b *gofpdfBackend) RoundedRect(x, y, w, h, r float64, style string) {
+	r = math.Min(r, math.Min(w, h)/2)
+	k := r * 0.5523 // Bezier control point offset for a quarter circle
+
+	b.pdf.MoveTo(x+r, y)
+	b.pdf.LineTo(x+w-r, y)
+	b.pdf.CurveBezierCubicTo(x+w-r+k, y, x+w, y+r-k, x+w, y+r)
+	b.pdf.LineTo(x+w, y+h-r)
+	b.pdf.CurveBezierCubicTo(x+w, y+h-r+k, x+w-r+k, y+h, x+w-r, y+h)
+	b.pdf.LineTo(x+r, y+h)
+	b.pdf.CurveBezierCubicTo(x+r-k, y+h, x, y+h-r+k, x, y+h-r)
+	b.pdf.LineTo(x, y+r)
+	b.pdf.CurveBezierCubicTo(x, y+r-k, x+r-k, y, x+r, y)
+	b.pdf.ClosePath()
+	b.pdf.DrawPath(style)
+}
+
+func (b *gofpdfBackend) Circle(x, y, r float64, style string) {
+	b.pdf.Circle(x, y, r, style)
+}
+
+func (b *gofpdfBackend) ImageSize(path string) (float64, float64, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, 0, err
+	}
+	info := b.pdf.RegisterImageOptions(path, imageOptions(path))
+	if err := b.pdf.Error(); err != nil {
+		b.pdf.ClearError()
+		return 0, 0, err
+	}
+	// Without the image's DPI, gofpdf sizes it at 72 pixels per inch
+	return math.Round(info.Width() / ptToMM), math.Round(info.Height() / ptToMM), nil
+}
+
+func (b *gofpdfBackend) Image(path string, x, y, w, h float64) error {
+	b.pdf.ImageOptions(path, x, y, w, h, false, imageOptions(path), 0, "")
+	return b.pdf.Error()
+}
+
+// imageOptions gives the image's type, since gofpdf otherwise takes it from
+// the file extension, which media files in exports often lack
+func imageOptions(path string) gofpdf.ImageOptions {
+	return gofpdf.ImageOptions{ImageType: imageFormat(path), ReadDpi: false}
+}
+
+func (b *gofpdfBackend) Link(x, y, w, h float64, url string) {
+	b.pdf.LinkString(x, y, w, h, url)
+}
+
+func (b *gofpdfBackend) Err() error {
+	return b.pdf.Error()
+}
+
+func (b *gofpdfBackend) Output(w io.Writer) error {
+	return b.pdf.Output(w)
+}
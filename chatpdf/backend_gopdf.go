@@ -0,0 +1,255 @@
+package chatpdf
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/signintech/gopdf"
+)
+
+// gopdfPageSizes maps page size names to gopdf page sizes
+var gopdfPageSizes = map[string]*gopdf.Rect{
+	"A3":     gopdf.PageSizeA3,
+	"A4":     gopdf.PageSizeA4,
+	"A5":     gopdf.PageSizeA5,
+	"Letter": gopdf.PageSizeLetter,
+	"Legal":  gopdf.PageSizeLegal,
+}
+
+// errNoBuiltinFont is reported when text is drawn without a TrueType font
+var errNoBuiltinFont = errors.New("gopdf backend has no built-in font; a TrueType font is required")
+
+// gopdfState is the drawing state that page hooks must not disturb
+type gopdfState struct {
+	family, style string
+	size          float64
+	text          Color
+	fill, draw    Color
+	lineWidth     float64
+}
+
+// gopdfBackend draws with github.com/signintech/gopdf. gopdf does not save
+// the drawing state around its header and footer callbacks, so the backend
+// tracks it and restores it after the hooks run.
+type gopdfBackend struct {
+	pdf    *gopdf.GoPdf
+	width  float64
+	height float64
+	pages  int
+	state  gopdfState
+	err    error
+}
+
+// newGopdfBackend creates a gopdf document with pages of the given size
+func newGopdfBackend(pageSize string) (Backend, error) {
+	size, ok := gopdfPageSizes[pageSize]
+	if !ok {
+		return nil, fmt.Errorf("gopdf backend does not support page size %q", pageSize)
+	}
+
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{Unit: gopdf.UnitMM, PageSize: *size})
+
+	return &gopdfBackend{
+		pdf:    pdf,
+		width:  gopdf.PointsToUnits(gopdf.UnitMM, size.W),
+		height: gopdf.PointsToUnits(gopdf.UnitMM, size.H),
+	}, nil
+}
+
+// setErr records the first error
+func (b *gopdfBackend) setErr(err error) {
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *gopdfBackend) PageSize() (float64, float64) {
+	return b.width, b.height
+}
+
+func (b *gopdfBackend) AddPage() {
+	b.pages++
+	b.pdf.AddPage()
+}
+
+func (b *gopdfBackend) PageNo() int {
+	return b.pages
+}
+
+func (b *gopdfBackend) SetPageHooks(header, footer func()) {
+	b.pdf.AddHeader(b.preserveState(header))
+	b.pdf.AddFooter(b.preserveState(footer))
+}
+
+// preserveState wraps a page hook so the drawing state is restored after it
+func (b *gopdfBackend) preserveState(hook func()) func() {
+	if hook == nil {
+		return nil
+	}
+	return func() {
+		saved := b.state
+		hook()
+		if saved.family != "" {
+			b.SetFont(saved.family, saved.style, saved.size)
+		}
+		b.SetTextColor(saved.text)
+		b.SetFillColor(saved.fill)
+		b.SetDrawColor(saved.draw)
+		if saved.lineWidth > 0 {
+			b.SetLineWidth(saved.lineWidth)
+		}
+	}
+}
+
+func (b *gopdfBackend) TotalPagesAlias() string {
+	return ""
+}
+
+func (b *gopdfBackend) AddFont(family, style, path string) error {
+	opt := gopdf.TtfOption{Style: gopdf.Regular}
+	if strings.Contains(style, "B") {
+		opt.Style |= gopdf.Bold
+	}
+	if strings.Contains(style, "I") {
+		opt.Style |= gopdf.Italic
+	}
+	return b.pdf.AddTTFFontWithOption(family, path, opt)
+}
+
+func (b *gopdfBackend) SetFont(family, style string, size float64) {
+	b.state.family, b.state.style, b.state.size = family, style, size
+	if family == "" {
+		b.setErr(errNoBuiltinFont)
+		return
+	}
+	b.setErr(b.pdf.SetFont(family, style, size))
+}
+
+func (b *gopdfBackend) SetTextColor(c Color) {
+	b.state.text = c
+	b.pdf.SetTextColor(uint8(c.R), uint8(c.G), uint8(c.B))
+}
+
+func (b *gopdfBackend) StringWidth(s string) float64 {
+	if b.state.family == "" {
+		return 0
+	}
+	w, err := b.pdf.MeasureTextWidth(s)
+	b.setErr(err)
+	return w
+}
+
+func (b *gopdfBackend) Text(x, y float64, s string) {
+	if b.state.family == "" {
+		return
+	}
+	b.pdf.SetXY(x, y)
+	b.setErr(b.pdf.Text(s))
+}
+
+func (b *gopdfBackend) SetFillColor(c Color) {
+	b.state.fill = c
+	b.pdf.SetFillColor(uint8(c.R), uint8(c.G), uint8(c.B))
+}
+
+func (b *gopdfBackend) SetDrawColor(c Color) {
+	b.state.draw = c
+	b.pdf.SetStrokeColor(uint8(c.R), uint8(c.G), uint8(c.B))
+}
+
+func (b *gopdfBackend) SetLineWidth(width float64) {
+	b.state.lineWidth = width
+	b.pdf.SetLineWidth(width)
+}
+
+func (b *gopdfBackend) Line(x1, y1, x2, y2 float64) {
+	b.pdf.Line(x1, y1, x2, y2)
+}
+
+func (b *gopdfBackend) Rect(x, y, w, h float64, style string) {
+	b.pdf.RectFromUpperLeftWithStyle(x, y, w, h, style)
+}
+
+func (b *gopdfBackend) RoundedRect(x, y, w, h, r float64, style string) {
+	if r > w/2 {
+		r = w / 2
+	}
+	if r > h/2 {
+		r = h / 2
+	}
+	b.setErr(b.pdf.Rectangle(x, y, x+w, y+h, style, r, 6))
+}
+
+func (b *gopdfBackend) Circle(x, y, r float64, style string) {
+	// A rectangle whose corner radius is half its side is a circle
+	b.setErr(b.pdf.Rectangle(x-r, y-r, x+r, y+r, style, r, 12))
+}
+
+func (b *gopdfBackend) ImageSize(path string) (float64, float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return float64(cfg.Width), float64(cfg.Height), nil
+}
+
+func (b *gopdfBackend) Image(path string, x, y, w, h float64) error {
+	var err error
+	if imageFormat(path) == "gif" {
+		// gopdf only embeds PNG and JPEG files, so GIFs are decoded and
+		// embedded as PNG
+		var img image.Image
+		if img, err = decodeImage(path); err == nil {
+			err = b.pdf.ImageFrom(img, x, y, &gopdf.Rect{W: w, H: h})
+		}
+	} else {
+		err = b.pdf.Image(path, x, y, &gopdf.Rect{W: w, H: h})
+	}
+	b.setErr(err)
+	return err
+}
+
+// decodeImage reads an image file
+func decodeImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+func (b *gopdfBackend) Link(x, y, w, h float64, url string) {
+	b.pdf.AddExternalLink(url, x, y, w, h)
+}
+
+func (b *gopdfBackend) Err() error {
+	return b.err
+}
+
+func (b *gopdfBackend) Output(w io.Writer) error {
+	if b.err != nil {
+		return b.err
+	}
+	_, err := b.pdf.WriteTo(w)
+	return err
+}
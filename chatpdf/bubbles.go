@@ -0,0 +1,203 @@
+package chatpdf
+
+import (
+	"strings"
+	"time"
+)
+
+// Layout selects how entries are arranged on the page
+type Layout string
+
+// Supported layouts
+const (
+	LayoutClassic Layout = "classic" // a name and time line above each full width message
+	LayoutBubbles Layout = "bubbles" // messenger style bubbles, the self user's on the right
+)
+
+// layouts lists the supported layouts
+var layouts = []Layout{LayoutClassic, LayoutBubbles}
+
+// Layouts returns the names of the supported layouts
+func Layouts() []string {
+	names := make([]string, len(layouts))
+	for i, l := range layouts {
+		names[i] = string(l)
+	}
+	return names
+}
+
+// lookupLayout validates a layout, defaulting to LayoutClassic
+func lookupLayout(l Layout) (Layout, error) {
+	if l == "" {
+		return LayoutClassic, nil
+	}
+	for _, known := range layouts {
+		if strings.EqualFold(string(known), string(l)) {
+			return known, nil
+		}
+	}
+	return "", &OptionError{Option: "Layout", Value: string(l), Valid: Layouts()}
+}
+
+// bubbleGroupGap is the longest pause between two messages from the same
+// sender that still groups them under one name line
+const bubbleGroupGap = 5 * time.Minute
+
+// sameGroup reports whether item continues the group of messages that prev
+// belongs to. Replies that quote their parent start a new group.
+func sameGroup(prev *threadItem, item threadItem) bool {
+	if prev == nil || item.parent != nil || prev.depth != item.depth {
+		return false
+	}
+	p, entry := prev.entry, item.entry
+	if p.Kind == KindSystem || entry.Kind == KindSystem || p.User != entry.User {
+		return false
+	}
+	return entry.Timestamp.Sub(p.Timestamp) < bubbleGroupGap
+}
+
+// isSelf reports whether an entry was written by the self user
+func (g *PDFGenerator) isSelf(entry ChatEntry) bool {
+	return g.selfUser != "" && strings.EqualFold(entry.User, g.selfUser)
+}
+
+// addBubble draws an entry as a chat bubble sized to its text: on the right
+// for the self user and on the left, beside the avatar, for everyone else.
+// Consecutive messages from one sender share the name and time line and
+// the avatar of the first. Nested replies on the left are indented.
+func (g *PDFGenerator) addBubble(item threadItem, prev *threadItem) {
+	const fontSize = 11
+	const metaHeight = 5.0
+	const pad, radius = 2.5, 3.0
+	const groupGap, entryGap = 1.5, 5.0
+
+	entry := item.entry
+	if entry.Kind == KindSystem {
+		g.addSystemEntry(entry)
+		return
+	}
+	if entry.Kind == KindAction {
+		entry = actionEntry(entry)
+	}
+
+	grouped := sameGroup(prev, item)
+	self := g.isSelf(entry)
+	if grouped {
+		g.y += groupGap - entryGap
+	}
+
+	indent := float64(item.depth) * threadIndent
+	left := g.margin
+	column := g.pageWidth - 2*g.margin - indent
+	if !self {
+		left += indent
+	}
+	avatarX := left
+	if !g.hideAvatars {
+		left += avatarSize + avatarGap
+		column -= avatarSize + avatarGap
+	}
+	maxWidth := column * 0.75
+
+	// Measure the text to fit the bubble to it; bubbles with code blocks
+	// take the full width
+	textWidth := maxWidth - 2*pad
+	if !hasCodeBlock(entry.Spans) {
+		g.setFont("", fontSize)
+		runs := styledRuns(entry.Message, entry.Spans, g.entryEmoji(entry))
+		textWidth = 0
+		for _, l := range g.wrapRuns(runs, maxWidth-2*pad, fontSize) {
+			if l.width > textWidth {
+				textWidth = l.width
+			}
+		}
+	}
+	bubbleWidth := textWidth + 2*pad
+	x := left
+	if self {
+		x = g.pageWidth - g.margin - bubbleWidth
+	}
+
+	// Keep the name line together with the first line of the bubble
+	first := fontSize * ptToMM * lineSpacing
+	if !grouped {
+		first += metaHeight
+	}
+	if item.parent != nil {
+		first += quoteHeight
+	}
+	if g.y+first+2*pad > g.contentBottom() {
+		g.newPage()
+	}
+
+	top := g.y
+	if !grouped {
+		g.addBubbleMeta(entry, self, left, top+metaHeight*0.7)
+		if !self && !g.hideAvatars {
+			g.drawAvatar(entry, avatarX, top)
+		}
+		g.y += metaHeight
+	}
+	if item.parent != nil {
+		quoteX := left
+		if self {
+			quoteX = g.pageWidth - g.margin - maxWidth
+		}
+		g.addQuote(item.parent, quoteX, maxWidth)
+	}
+
+	fill := g.theme.Meta.mix(g.theme.Background, 0.85)
+	if color, ok := entryColor(entry); ok {
+		fill = color.mix(g.theme.Background, 0.8)
+	} else if self {
+		fill = g.theme.Link.mix(g.theme.Background, 0.8)
+	}
+	page := g.backend.PageNo()
+	if entry.Message != "" || len(entry.Attachments) == 0 {
+		g.setTextColor(g.theme.Text)
+		g.drawMessage(entry, x+pad, textWidth, fontSize, pad, func(top, height float64) {
+			g.backend.SetFillColor(fill)
+			g.backend.RoundedRect(x, top, bubbleWidth, height, radius, "F")
+		})
+	}
+
+	// Attachments and reactions go below the bubble, on the same side
+	extraX, extraWidth := left, maxWidth
+	if self {
+		extraX = g.pageWidth - g.margin - maxWidth
+	}
+	g.addAttachments(entry, extraX, extraWidth, self)
+	g.addReactions(entry, extraX, extraWidth)
+
+	if !grouped && !self && !g.hideAvatars && g.backend.PageNo() == page && g.y < top+avatarSize {
+		g.y = top + avatarSize
+	}
+	g.y += entryGap
+}
+
+// addBubbleMeta draws the name and time above the first bubble of a group;
+// the self user's bubbles only get the time, on the right
+func (g *PDFGenerator) addBubbleMeta(entry ChatEntry, self bool, x, baseline float64) {
+	var timestamp string
+	if !entry.Timestamp.IsZero() {
+		timestamp = entry.Timestamp.Format("2006-01-02 15:04")
+	}
+
+	if self {
+		g.setFont("", 9)
+		g.setTextColor(g.theme.Meta)
+		g.backend.Text(g.pageWidth-g.margin-g.stringWidth(timestamp), baseline, timestamp)
+		return
+	}
+
+	g.setFont("B", 10)
+	g.setTextColor(g.theme.Title)
+	g.backend.Text(x, baseline, entry.User)
+	nameWidth := g.stringWidth(entry.User)
+	if entry.User != "" {
+		nameWidth += 3
+	}
+	g.setFont("", 9)
+	g.setTextColor(g.theme.Meta)
+	g.backend.Text(x+nameWidth, baseline, timestamp)
+}
@@ -0,0 +1,65 @@
+package chatpdf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSameGroup(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	msg := func(user string, after time.Duration) ChatEntry {
+		return ChatEntry{Timestamp: start.Add(after), User: user, Message: "hi"}
+	}
+	first := threadItem{entry: msg("alice", 0)}
+	tests := []struct {
+		name string
+		prev *threadItem
+		item threadItem
+		want bool
+	}{
+		{"first item", nil, first, false},
+		{"same sender soon after", &first, threadItem{entry: msg("alice", time.Minute)}, true},
+		{"just under the gap", &first, threadItem{entry: msg("alice", bubbleGroupGap-time.Second)}, true},
+		{"at the gap", &first, threadItem{entry: msg("alice", bubbleGroupGap)}, false},
+		{"other sender", &first, threadItem{entry: msg("bob", time.Minute)}, false},
+		{"other depth", &first, threadItem{entry: msg("alice", time.Minute), depth: 1}, false},
+		{"reply with a quote", &first, threadItem{entry: msg("alice", time.Minute), parent: &first.entry}, false},
+		{"system entry", &first, threadItem{entry: ChatEntry{Timestamp: start, User: "alice", Kind: KindSystem}}, false},
+		{"after a system entry", &threadItem{entry: ChatEntry{Timestamp: start, User: "alice", Kind: KindSystem}}, threadItem{entry: msg("alice", time.Minute)}, false},
+		{"actions group with messages", &first, threadItem{entry: ChatEntry{Timestamp: start.Add(time.Minute), User: "alice", Kind: KindAction}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameGroup(tt.prev, tt.item); got != tt.want {
+				t.Errorf("sameGroup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupLayout(t *testing.T) {
+	tests := []struct {
+		in   Layout
+		want Layout
+	}{
+		{"", LayoutClassic},
+		{"classic", LayoutClassic},
+		{"Bubbles", LayoutBubbles},
+	}
+	for _, tt := range tests {
+		got, err := lookupLayout(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("lookupLayout(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	_, err := lookupLayout("cards")
+	var optErr *OptionError
+	if !errors.As(err, &optErr) || optErr.Option != "Layout" || optErr.Value != "cards" {
+		t.Fatalf("lookupLayout(cards) error = %v, want an OptionError for Layout", err)
+	}
+	if want := `unknown layout "cards" (available: classic, bubbles)`; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,309 @@
+package chatpdf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CodeOverflow selects what happens to code lines wider than their block
+type CodeOverflow string
+
+// Supported code overflows
+const (
+	CodeWrap CodeOverflow = "wrap" // long lines continue on the next line
+	CodeClip CodeOverflow = "clip" // long lines are cut off at the edge of the block
+)
+
+// codeOverflows lists the supported code overflows
+var codeOverflows = []CodeOverflow{CodeWrap, CodeClip}
+
+// CodeOverflows returns the names of the supported code overflows
+func CodeOverflows() []string {
+	names := make([]string, len(codeOverflows))
+	for i, o := range codeOverflows {
+		names[i] = string(o)
+	}
+	return names
+}
+
+// lookupCodeOverflow validates a code overflow, defaulting to CodeWrap
+func lookupCodeOverflow(o CodeOverflow) (CodeOverflow, error) {
+	if o == "" {
+		return CodeWrap, nil
+	}
+	for _, known := range codeOverflows {
+		if strings.EqualFold(string(known), string(o)) {
+			return known, nil
+		}
+	}
+	return "", &OptionError{Option: "CodeOverflow", Value: string(o), Valid: CodeOverflows()}
+}
+
+// Code block geometry in mm
+const (
+	codePad    = 2.0 // between the edge of the block and the code
+	codeGap    = 1.5 // between the block and the text around it
+	codeRadius = 1.5
+)
+
+// codeFontScale is the size of code relative to the message text
+const codeFontScale = 0.85
+
+// tabWidth is the number of spaces a tab in code is expanded to
+const tabWidth = 4
+
+// Token colors for light and dark backgrounds; plain code is drawn in the
+// theme's text color
+var (
+	lightCodeColors = map[tokenKind]Color{
+		tokenKeyword: {215, 58, 73},
+		tokenBuiltin: {111, 66, 193},
+		tokenString:  {3, 47, 98},
+		tokenNumber:  {0, 92, 197},
+		tokenComment: {106, 115, 125},
+	}
+	darkCodeColors = map[tokenKind]Color{
+		tokenKeyword: {255, 123, 114},
+		tokenBuiltin: {210, 168, 255},
+		tokenString:  {165, 214, 255},
+		tokenNumber:  {121, 192, 255},
+		tokenComment: {139, 148, 158},
+	}
+)
+
+// codeColors returns the color of each token kind for the theme
+func (g *PDFGenerator) codeColors() map[tokenKind]*Color {
+	palette := lightCodeColors
+	if g.theme.Background.isDark() {
+		palette = darkCodeColors
+	}
+	text := g.theme.Text
+	colors := map[tokenKind]*Color{tokenPlain: &text}
+	for kind, c := range palette {
+		colors[kind] = &c
+	}
+	return colors
+}
+
+// messagePart is a piece of a message: text with its spans, or a code block
+type messagePart struct {
+	text     string
+	spans    []Span // offsets into text
+	code     bool
+	language string
+}
+
+// hasCodeBlock reports whether any of the spans marks a code block
+func hasCodeBlock(spans []Span) bool {
+	for _, s := range spans {
+		if s.Style&StyleCodeBlock != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCodeBlocks cuts a message into text and the code blocks marked by
+// StyleCodeBlock spans. Code blocks are drawn on lines of their own, so the
+// line breaks around them are dropped.
+func splitCodeBlocks(text string, spans []Span) []messagePart {
+	var blocks []Span
+	for _, s := range spans {
+		if s.Style&StyleCodeBlock == 0 {
+			continue
+		}
+		s.Start, s.End = clampOffset(s.Start, text), clampOffset(s.End, text)
+		if s.End > s.Start {
+			blocks = append(blocks, s)
+		}
+	}
+	if len(blocks) == 0 {
+		return []messagePart{{text: text, spans: spans}}
+	}
+
+	// Overlapping blocks become one, in the language of the first
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+	merged := blocks[:1]
+	for _, b := range blocks[1:] {
+		last := &merged[len(merged)-1]
+		if b.Start < last.End {
+			last.End = max(last.End, b.End)
+			continue
+		}
+		merged = append(merged, b)
+	}
+
+	var parts []messagePart
+	pos := 0
+	for _, b := range merged {
+		parts = appendTextPart(parts, text, spans, pos, b.Start)
+		code := strings.TrimSuffix(text[b.Start:b.End], "\n")
+		parts = append(parts, messagePart{text: code, code: true, language: b.Language})
+		pos = b.End
+	}
+	return appendTextPart(parts, text, spans, pos, len(text))
+}
+
+// appendTextPart adds text[start:end] to parts with the spans that style it,
+// leaving out a line break next to a code block
+func appendTextPart(parts []messagePart, text string, spans []Span, start, end int) []messagePart {
+	if start > 0 && start < end && text[start] == '\n' {
+		start++
+	}
+	if end < len(text) && start < end && text[end-1] == '\n' {
+		end--
+	}
+	if start >= end {
+		return parts
+	}
+
+	part := messagePart{text: text[start:end]}
+	for _, s := range spans {
+		if s.Style&StyleCodeBlock != 0 {
+			continue
+		}
+		s.Start, s.End = max(s.Start, start)-start, min(s.End, end)-start
+		if s.End > s.Start {
+			part.spans = append(part.spans, s)
+		}
+	}
+	return append(parts, part)
+}
+
+// drawMessage draws the entry's message in a column of the given width:
+// text is wrapped with drawLines and code blocks are drawn by addCodeBlock.
+// decorate and pad are passed on to drawLines for each part.
+func (g *PDFGenerator) drawMessage(entry ChatEntry, x, width, fontSize, pad float64, decorate decoration) {
+	for _, part := range splitCodeBlocks(entry.Message, entry.Spans) {
+		if part.code {
+			g.addCodeBlock(part, x, width, fontSize, pad, decorate)
+			continue
+		}
+		g.setFont("", fontSize)
+		runs := styledRuns(part.text, part.spans, g.entryEmoji(entry))
+		g.drawLines(g.wrapRuns(runs, width, fontSize), x, fontSize, pad, decorate)
+	}
+}
+
+// addCodeBlock draws a code block in the monospace font on a shaded
+// background, coloring its tokens when its language is known. Lines wider
+// than the block wrap or are clipped according to the code overflow, and a
+// gutter on the left numbers them when line numbers are on. decorate and pad
+// are those of the message around the block, such as its bubble.
+func (g *PDFGenerator) addCodeBlock(part messagePart, x, width, fontSize, pad float64, decorate decoration) {
+	size := fontSize * codeFontScale
+	colors := g.codeColors()
+	meta := g.theme.Meta
+
+	src := strings.ReplaceAll(part.text, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", strings.Repeat(" ", tabWidth))
+	source := [][]codeToken{nil}
+	for _, t := range lexCode(src, lookupCodeLanguage(part.language)) {
+		for i, text := range strings.Split(t.text, "\n") {
+			if i > 0 {
+				source = append(source, nil)
+			}
+			if text != "" {
+				source[len(source)-1] = append(source[len(source)-1], codeToken{text, t.kind})
+			}
+		}
+	}
+
+	// Measure in the monospace font
+	g.setFont("", size)
+	g.useRunFont(StyleCodeBlock)
+	defer g.backend.SetFont(g.fontFamily, g.fontStyle, g.fontSize)
+
+	digits := len(strconv.Itoa(len(source)))
+	var gutter float64
+	if g.lineNumbers {
+		gutter = g.stringWidth(strings.Repeat("0", digits+1))
+	}
+	avail := width - 2*codePad - gutter
+	ellipsis := g.stringWidth("…")
+
+	// newLine starts a line with the gutter, numbered unless it continues a
+	// wrapped line
+	newLine := func(number int) line {
+		if !g.lineNumbers {
+			return line{}
+		}
+		label := ""
+		if number > 0 {
+			label = fmt.Sprintf("%*d", digits, number)
+		}
+		return line{fragments: []fragment{{textRun: textRun{text: label, style: StyleCodeBlock, color: &meta}, width: gutter}}, width: gutter}
+	}
+	// add appends code to a line, never merging it into the gutter
+	add := func(l *line, frag fragment) {
+		if g.lineNumbers && len(l.fragments) == 1 {
+			l.fragments = append(l.fragments, frag)
+			l.width += frag.width
+			return
+		}
+		l.append(frag)
+	}
+
+	var lines []line
+	for n, tokens := range source {
+		cur := newLine(n + 1)
+		limit, clip := avail, false
+		if g.codeOverflow == CodeClip {
+			var text strings.Builder
+			for _, t := range tokens {
+				text.WriteString(t.text)
+			}
+			if g.stringWidth(text.String()) > avail {
+				limit, clip = avail-ellipsis, true
+			}
+		}
+
+		var used, chunkWidth float64
+		var chunk strings.Builder
+		var kind tokenKind
+		flush := func() {
+			if chunk.Len() > 0 {
+				add(&cur, fragment{textRun: textRun{text: chunk.String(), style: StyleCodeBlock, color: colors[kind]}, width: chunkWidth})
+				chunk.Reset()
+				chunkWidth = 0
+			}
+		}
+	tokens:
+		for _, t := range tokens {
+			kind = t.kind
+			for _, r := range t.text {
+				w := g.stringWidth(string(r))
+				if used > 0 && used+w > limit {
+					flush()
+					if clip {
+						break tokens
+					}
+					lines = append(lines, cur)
+					cur, used = newLine(0), 0
+				}
+				chunk.WriteRune(r)
+				chunkWidth += w
+				used += w
+			}
+			flush()
+		}
+		if clip {
+			add(&cur, fragment{textRun: textRun{text: "…", style: StyleCodeBlock, color: &meta}, width: ellipsis})
+		}
+		lines = append(lines, cur)
+	}
+
+	fill := g.theme.Background.mix(meta, 0.08)
+	inset := pad + codeGap
+	g.drawLines(lines, x+codePad, size, inset+codePad, func(top, height float64) {
+		if decorate != nil {
+			decorate(top, height)
+		}
+		g.backend.SetFillColor(fill)
+		g.backend.SetDrawColor(g.theme.Rule)
+		g.backend.SetLineWidth(0.2)
+		g.backend.RoundedRect(x, top+inset, width, height-2*inset, codeRadius, "FD")
+	})
+}
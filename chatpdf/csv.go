@@ -0,0 +1,274 @@
+package chatpdf
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvFields lists the entry fields that columns can be mapped to
+var csvFields = []string{"timestamp", "user", "message", "color", "icon"}
+
+// csvHeaders lists the header names each field is found under when no
+// column is mapped to it, most specific first
+var csvHeaders = map[string][]string{
+	"timestamp": {"timestamp", "datetime", "date/time", "created_at", "created", "sent", "date", "time"},
+	"user":      {"user", "author", "from", "sender", "name", "username", "nick"},
+	"message":   {"message", "text", "body", "content", "msg"},
+	"color":     {"color", "colour"},
+	"icon":      {"icon", "avatar", "avatar_url"},
+}
+
+// csvTimeLayouts are tried in order when no time layout is given
+var csvTimeLayouts = append(append([]string{}, timestampLayouts...),
+	"2006-01-02T15:04",
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	time.RFC1123Z,
+	time.RFC1123,
+)
+
+// ImportCSV reads messages from a CSV or TSV file with one message per row.
+// The delimiter (comma, semicolon or tab) is detected from the first line.
+// opts.Columns maps the fields "timestamp", "user", "message", "color" and
+// "icon" to columns by header name or 1-based index; unmapped fields are
+// looked up under common header names such as "date", "author" and "text".
+// A first row whose timestamp cell is not a time is taken as the header.
+// Times are parsed with opts.TimeLayout, or common layouts and Unix
+// seconds, in opts.Location unless they name a zone. Bad rows are reported
+// with their line number and column.
+func ImportCSV(path string, opts ImportOptions) ([]ChatEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = csvDelimiter(path, data)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	first, err := r.Read()
+	if err == io.EOF {
+		return nil, pathError(path, errors.New("no rows found"))
+	}
+	if err != nil {
+		return nil, pathError(path, err)
+	}
+	cols, header, err := opts.csvColumns(first)
+	if err != nil {
+		return nil, pathError(path, err)
+	}
+
+	var entries []ChatEntry
+	for row := first; ; {
+		if !header && !(len(row) == 1 && strings.TrimSpace(row[0]) == "") {
+			line, _ := r.FieldPos(0)
+			entry, err := cols.entry(row, opts)
+			if err != nil {
+				return nil, pathError(path, &LineError{Line: line, Err: err})
+			}
+			if opts.inRange(entry.Timestamp) {
+				entries = append(entries, entry)
+			}
+		}
+		header = false
+
+		row, err = r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, pathError(path, err)
+		}
+	}
+	return entries, nil
+}
+
+// csvDelimiter returns tab for .tsv files, and otherwise the most frequent
+// of comma, semicolon and tab on the first line
+func csvDelimiter(path string, data []byte) rune {
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		return '\t'
+	}
+	first := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		first = data[:i]
+	}
+	best, bestCount := ',', bytes.Count(first, []byte(","))
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(first, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// csvColumns holds the 0-based column of each field, or -1 when the field
+// has no column; names holds the column names for error messages
+type csvColumns struct {
+	index map[string]int
+	names map[string]string
+}
+
+// csvColumns resolves the column mapping against the first row and reports
+// whether that row is a header
+func (opts ImportOptions) csvColumns(first []string) (csvColumns, bool, error) {
+	cols := csvColumns{index: make(map[string]int), names: make(map[string]string)}
+	for field := range opts.Columns {
+		if _, ok := csvHeaders[strings.ToLower(field)]; !ok {
+			return cols, false, &OptionError{Option: "Field", Value: field, Valid: csvFields}
+		}
+	}
+
+	headers := make([]string, len(first))
+	for i, h := range first {
+		headers[i] = strings.TrimSpace(h)
+	}
+	byName := func(name string) int {
+		for i, h := range headers {
+			if strings.EqualFold(h, name) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	header := false
+	for _, field := range csvFields {
+		col := -1
+		var ref string
+		for k, v := range opts.Columns {
+			if strings.EqualFold(k, field) {
+				ref = strings.TrimSpace(v)
+			}
+		}
+		switch n, err := strconv.Atoi(ref); {
+		case ref == "":
+			for _, name := range csvHeaders[field] {
+				if col = byName(name); col >= 0 {
+					header = true
+					break
+				}
+			}
+			// A date column next to a time column holds only part of
+			// the timestamp, so neither is picked on its own
+			if field == "timestamp" && col >= 0 {
+				if date, tm := byName("date"), byName("time"); date >= 0 && tm >= 0 && (col == date || col == tm) {
+					return cols, false, fmt.Errorf(`both %q and %q columns could hold the timestamp; map one with the "timestamp" field`, headers[date], headers[tm])
+				}
+			}
+		case err == nil:
+			if n < 1 {
+				return cols, false, fmt.Errorf("column index %d for %s must be at least 1", n, field)
+			}
+			col = n - 1
+		default:
+			if col = byName(ref); col < 0 {
+				return cols, false, &OptionError{Option: "Column", Value: ref, Valid: headers}
+			}
+			header = true
+		}
+		cols.index[field] = col
+	}
+
+	if cols.index["message"] < 0 {
+		return cols, false, errors.New(`no message column found; map one with the "message" field`)
+	}
+
+	// With columns given by index only, the first row is a header when its
+	// timestamp cell is not a time
+	if !header {
+		if col := cols.index["timestamp"]; col >= 0 && col < len(first) {
+			if _, err := parseCSVTime(first[col], opts); err != nil {
+				header = true
+			}
+		}
+	}
+	for field, col := range cols.index {
+		cols.names[field] = "column " + strconv.Itoa(col+1)
+		if header && col >= 0 && col < len(headers) && headers[col] != "" {
+			cols.names[field] = headers[col]
+		}
+	}
+	return cols, header, nil
+}
+
+// cell returns the trimmed value of a field in row
+func (cols csvColumns) cell(row []string, field string) (string, error) {
+	col := cols.index[field]
+	if col < 0 {
+		return "", nil
+	}
+	if col >= len(row) {
+		return "", fmt.Errorf("missing %s column %q", field, cols.names[field])
+	}
+	return strings.TrimSpace(row[col]), nil
+}
+
+// entry converts a row to a chat entry
+func (cols csvColumns) entry(row []string, opts ImportOptions) (ChatEntry, error) {
+	values := make(map[string]string, len(csvFields))
+	for _, field := range csvFields {
+		v, err := cols.cell(row, field)
+		if err != nil {
+			return ChatEntry{}, err
+		}
+		values[field] = v
+	}
+
+	entry := ChatEntry{
+		User:     values["user"],
+		Message:  strings.ReplaceAll(values["message"], "\r\n", "\n"),
+		IconPath: values["icon"],
+	}
+	if values["timestamp"] != "" {
+		ts, err := parseCSVTime(values["timestamp"], opts)
+		if err != nil {
+			return ChatEntry{}, fmt.Errorf("column %q: %w", cols.names["timestamp"], err)
+		}
+		entry.Timestamp = ts
+	}
+	if values["color"] != "" {
+		r, g, b, err := parseHexColor(values["color"])
+		if err != nil {
+			return ChatEntry{}, fmt.Errorf("column %q: %w", cols.names["color"], err)
+		}
+		entry.R, entry.G, entry.B = r, g, b
+	}
+	return entry, nil
+}
+
+// parseCSVTime parses a timestamp cell with the options' layout, or with
+// common layouts and as Unix seconds
+func parseCSVTime(s string, opts ImportOptions) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	layouts := csvTimeLayouts
+	if opts.TimeLayout != "" {
+		layouts = []string{opts.TimeLayout}
+	} else if secs, err := strconv.ParseFloat(s, 64); err == nil && secs > 1e8 {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)), nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, opts.location()); err == nil {
+			return t, nil
+		}
+	}
+	if opts.TimeLayout != "" {
+		return time.Time{}, fmt.Errorf("invalid time %q for layout %q", s, opts.TimeLayout)
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,148 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		opts ImportOptions
+		want []string // time, user and message of each entry
+	}{
+		{
+			name: "common header names",
+			file: "chat.csv",
+			data: "Date,Author,Text\n2024-03-01 10:00:00,alice,hello\n2024-03-01 10:01:00,bob,\"hi, there\"\n",
+			want: []string{"2024-03-01 10:00 alice hello", "2024-03-01 10:01 bob hi, there"},
+		},
+		{
+			name: "full timestamp column before a time column",
+			file: "chat.csv",
+			data: "Time,Datetime,User,Message\n10:00,2024-03-01 10:00:00,alice,hello\n",
+			want: []string{"2024-03-01 10:00 alice hello"},
+		},
+		{
+			name: "separate date and time columns mapped",
+			file: "chat.csv",
+			data: "Date,Time,User,Message\n2024-03-01 10:00:00,10:00,alice,hello\n",
+			opts: ImportOptions{Columns: map[string]string{"timestamp": "Date"}},
+			want: []string{"2024-03-01 10:00 alice hello"},
+		},
+		{
+			name: "semicolons",
+			file: "chat.csv",
+			data: "time;user;message\n01.03.2024 10:00;alice;hello, world\n",
+			want: []string{"2024-03-01 10:00 alice hello, world"},
+		},
+		{
+			name: "tsv by extension",
+			file: "chat.tsv",
+			data: "sent\tfrom\tbody\n1709287200\talice\thello; hi\n",
+			want: []string{"2024-03-01 10:00 alice hello; hi"},
+		},
+		{
+			name: "columns by index without header",
+			file: "chat.csv",
+			data: "2024-03-01T10:00:00Z,alice,hello\n",
+			opts: ImportOptions{Columns: map[string]string{"timestamp": "1", "user": "2", "message": "3"}},
+			want: []string{"2024-03-01 10:00 alice hello"},
+		},
+		{
+			name: "columns by index with a header row",
+			file: "chat.csv",
+			data: "When,Who,What\n2024-03-01T10:00:00Z,alice,hello\n",
+			opts: ImportOptions{Columns: map[string]string{"timestamp": "1", "user": "2", "message": "3"}},
+			want: []string{"2024-03-01 10:00 alice hello"},
+		},
+		{
+			name: "columns by header name",
+			file: "chat.csv",
+			data: "When,Who,What\n3/1/2024 10:00 AM,alice,hello\n",
+			opts: ImportOptions{Columns: map[string]string{"timestamp": "When", "user": "Who", "message": "What"}},
+			want: []string{"2024-03-01 10:00 alice hello"},
+		},
+		{
+			name: "custom time layout",
+			file: "chat.csv",
+			data: "time,user,message\n01/03/24 10h00,alice,hello\n",
+			opts: ImportOptions{TimeLayout: "02/01/06 15h04"},
+			want: []string{"2024-03-01 10:00 alice hello"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			tt.opts.Location = time.UTC
+			entries, err := ImportCSV(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Timestamp.UTC().Format("2006-01-02 15:04")+" "+e.User+" "+e.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts ImportOptions
+		want string
+	}{
+		{
+			name: "no message column",
+			data: "time,user\n2024-03-01 10:00:00,alice\n",
+			want: "no message column",
+		},
+		{
+			name: "unknown field",
+			data: "time,user,message\n",
+			opts: ImportOptions{Columns: map[string]string{"avatar": "1"}},
+			want: "avatar",
+		},
+		{
+			name: "unknown column",
+			data: "time,user,message\n",
+			opts: ImportOptions{Columns: map[string]string{"message": "text"}},
+			want: "text",
+		},
+		{
+			name: "separate date and time columns",
+			data: "Date,Time,User,Message\n2024-03-01,10:00,alice,hello\n",
+			want: `both "Date" and "Time" columns could hold the timestamp; map one with the "timestamp" field`,
+		},
+		{
+			name: "bad time with line and column",
+			data: "time,user,message\n2024-03-01 10:00:00,alice,hello\nyesterday,bob,hi\n",
+			want: `line 3: column "time": invalid time "yesterday"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chat.csv")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ImportCSV(path, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package chatpdf
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// discordExport is a channel export in the JSON format written by
+// DiscordChatExporter
+type discordExport struct {
+	Guild struct {
+		Name string `json:"name"`
+	} `json:"guild"`
+	Channel struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"channel"`
+	Messages []discordMessage `json:"messages"`
+}
+
+// discordAuthor is the sender of a message. Color is the color of their
+// highest role, "#rrggbb", or empty when they have none.
+type discordAuthor struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Nickname  string        `json:"nickname"`
+	Color     string        `json:"color"`
+	AvatarURL string        `json:"avatarUrl"`
+	Roles     []discordRole `json:"roles"`
+}
+
+// discordRole is a server role held by a message author
+type discordRole struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// discordEmoji is a Unicode or custom emoji; custom emoji have an ID
+type discordEmoji struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"imageUrl"`
+}
+
+// discordAttachment is a file uploaded with a message
+type discordAttachment struct {
+	URL           string `json:"url"`
+	FileName      string `json:"fileName"`
+	FileSizeBytes int64  `json:"fileSizeBytes"`
+}
+
+// discordEmbed is a rich link preview or bot card
+type discordEmbed struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Fields      []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
+}
+
+// discordMessage is one message of a channel export
+type discordMessage struct {
+	ID          string              `json:"id"`
+	Type        string              `json:"type"`
+	Timestamp   time.Time           `json:"timestamp"`
+	Content     string              `json:"content"`
+	Author      discordAuthor       `json:"author"`
+	Attachments []discordAttachment `json:"attachments"`
+	Embeds      []discordEmbed      `json:"embeds"`
+	Reactions   []struct {
+		Emoji discordEmoji `json:"emoji"`
+		Count int          `json:"count"`
+	} `json:"reactions"`
+	InlineEmojis []discordEmoji `json:"inlineEmojis"`
+	Mentions     []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Nickname string `json:"nickname"`
+	} `json:"mentions"`
+	Reference *struct {
+		MessageID string `json:"messageId"`
+	} `json:"reference"`
+}
+
+// discordMessageTypes lists the message types that are written by their
+// author; all other types are events such as members joining
+var discordMessageTypes = map[string]bool{
+	"":                   true,
+	"Default":            true,
+	"Reply":              true,
+	"ChatInputCommand":   true,
+	"ContextMenuCommand": true,
+	"ApplicationCommand": true,
+}
+
+// discordEvents describes event messages; the text follows the author's
+// name, and %s is replaced by the first mentioned user or the content
+var discordEvents = map[string]string{
+	"GuildMemberJoin":              "joined the server",
+	"RecipientAdd":                 "added %s to the group",
+	"RecipientRemove":              "removed %s from the group",
+	"Call":                         "started a call",
+	"ChannelNameChange":            "changed the channel name: %s",
+	"ChannelIconChange":            "changed the channel icon",
+	"ChannelPinnedMessage":         "pinned a message",
+	"ThreadCreated":                "started a thread: %s",
+	"UserPremiumGuildSubscription": "boosted the server",
+	"ChannelFollowAdd":             "followed a channel: %s",
+}
+
+// ImportDiscord reads a Discord channel export in DiscordChatExporter's JSON
+// format. Role colors become the entry color, Markdown formatting becomes
+// spans, replies get the replied-to message's ID as ParentID, events such
+// as members joining or pinned messages become KindSystem entries, embeds are
+// appended to the message text, and custom emoji are drawn as images when
+// the export was made with its media downloaded next to the JSON file.
+func ImportDiscord(path string, opts ImportOptions) ([]ChatEntry, error) {
+	var export discordExport
+	if err := readJSONFile(path, &export); err != nil {
+		return nil, err
+	}
+	if opts.Channel != "" && !strings.EqualFold(strings.TrimPrefix(opts.Channel, "#"), export.Channel.Name) && opts.Channel != export.Channel.ID {
+		return nil, &OptionError{Option: "Channel", Value: opts.Channel, Valid: []string{export.Channel.Name}}
+	}
+
+	// The export has no list of roles, so role mentions are named from the
+	// roles held by the authors
+	roles := make(map[string]string)
+	for _, msg := range export.Messages {
+		for _, r := range msg.Author.Roles {
+			roles[r.ID] = r.Name
+		}
+	}
+
+	base := filepath.Dir(path)
+	var entries []ChatEntry
+	for _, msg := range export.Messages {
+		if !opts.inRange(msg.Timestamp) {
+			continue
+		}
+		entries = append(entries, discordEntry(msg, base, roles))
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// discordMarkup matches raw mentions and custom emoji that were not already
+// rendered as text by the exporter
+var discordMarkup = regexp.MustCompile(`<(@!?|@&|#|a?:(\w+):)(\d+)>`)
+
+// discordEntry converts a Discord message to a chat entry; base is the
+// directory that relative media paths are resolved against, and roles maps
+// role IDs to names
+func discordEntry(msg discordMessage, base string, roles map[string]string) ChatEntry {
+	entry := ChatEntry{
+		Timestamp: msg.Timestamp,
+		User:      msg.Author.Nickname,
+		ID:        msg.ID,
+		IconPath:  localImage(base, msg.Author.AvatarURL),
+	}
+	if entry.User == "" {
+		entry.User = msg.Author.Name
+	}
+	if !discordMessageTypes[msg.Type] {
+		entry.Kind = KindSystem
+		entry.Message = discordEventText(msg, entry.User)
+		entry.User, entry.IconPath = "", ""
+		return entry
+	}
+	if msg.Type == "Reply" && msg.Reference != nil {
+		entry.ParentID = msg.Reference.MessageID
+	}
+	if r, g, b, err := parseHexColor(msg.Author.Color); err == nil {
+		entry.R, entry.G, entry.B = r, g, b
+	}
+
+	addEmoji := func(e discordEmoji) string {
+		if e.ID == "" {
+			return e.Name
+		}
+		code := ":" + e.Name + ":"
+		if img := localImage(base, e.ImageURL); img != "" {
+			if entry.Emoji == nil {
+				entry.Emoji = make(map[string]string)
+			}
+			entry.Emoji[code] = img
+		}
+		return code
+	}
+	for _, e := range msg.InlineEmojis {
+		addEmoji(e)
+	}
+
+	names := make(map[string]string, len(msg.Mentions))
+	for _, m := range msg.Mentions {
+		names[m.ID] = m.Nickname
+		if m.Nickname == "" {
+			names[m.ID] = m.Name
+		}
+	}
+	text := discordMarkup.ReplaceAllStringFunc(msg.Content, func(m string) string {
+		sub := discordMarkup.FindStringSubmatch(m)
+		switch {
+		case sub[2] != "":
+			return ":" + sub[2] + ":"
+		case sub[1] == "#":
+			return "#" + sub[3]
+		case sub[1] == "@&":
+			if name := roles[sub[3]]; name != "" {
+				return "@" + name
+			}
+			return "@role"
+		case names[sub[3]] != "":
+			return "@" + names[sub[3]]
+		}
+		return "@" + sub[3]
+	})
+
+	var parts []string
+	if text != "" {
+		// The text comes first, so its spans need no offset
+		text, entry.Spans = markdownText(text, markdownDiscord)
+		parts = append(parts, text)
+	}
+	for _, embed := range msg.Embeds {
+		parts = append(parts, embedText(embed)...)
+	}
+	entry.Message = strings.Join(parts, "\n")
+
+	for _, a := range msg.Attachments {
+		attachment := Attachment{Name: a.FileName, Size: a.FileSizeBytes}
+		if local := localFile(base, a.URL); local != "" {
+			attachment.Path = local
+		} else {
+			attachment.URL = a.URL
+		}
+		entry.Attachments = append(entry.Attachments, attachment)
+	}
+	for _, r := range msg.Reactions {
+		entry.Reactions = append(entry.Reactions, Reaction{Emoji: addEmoji(r.Emoji), Count: r.Count})
+	}
+	return entry
+}
+
+// discordEventText describes an event message, naming its author user
+func discordEventText(msg discordMessage, user string) string {
+	format, ok := discordEvents[msg.Type]
+	if !ok {
+		if msg.Content != "" {
+			return user + ": " + msg.Content
+		}
+		return user + ": " + msg.Type
+	}
+	if !strings.Contains(format, "%s") {
+		return user + " " + format
+	}
+
+	arg := msg.Content
+	if msg.Type == "RecipientAdd" || msg.Type == "RecipientRemove" {
+		if len(msg.Mentions) == 0 || msg.Mentions[0].ID == msg.Author.ID {
+			if msg.Type == "RecipientRemove" {
+				return user + " left the group"
+			}
+			return user + " joined the group"
+		}
+		arg = msg.Mentions[0].Nickname
+		if arg == "" {
+			arg = msg.Mentions[0].Name
+		}
+	}
+	if arg == "" {
+		// Drop the ": %s" of descriptions such as a new channel name
+		format, _, _ = strings.Cut(format, ":")
+		return user + " " + format
+	}
+	return user + " " + fmt.Sprintf(format, arg)
+}
+
+// embedText renders an embed as quoted lines of text
+func embedText(embed discordEmbed) []string {
+	var lines []string
+	switch {
+	case embed.Title != "" && embed.URL != "":
+		lines = append(lines, embed.Title+" ("+embed.URL+")")
+	case embed.Title != "":
+		lines = append(lines, embed.Title)
+	case embed.URL != "":
+		lines = append(lines, embed.URL)
+	}
+	if embed.Description != "" {
+		lines = append(lines, strings.Split(embed.Description, "\n")...)
+	}
+	for _, f := range embed.Fields {
+		lines = append(lines, f.Name+": "+f.Value)
+	}
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return lines
+}
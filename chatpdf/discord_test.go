@@ -0,0 +1,54 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportDiscord(t *testing.T) {
+	export := `{
+  "guild": {"name": "Gophers"},
+  "channel": {"id": "42", "name": "general"},
+  "messages": [
+    {"id": "1", "type": "GuildMemberJoin", "timestamp": "2024-03-01T10:00:00+00:00", "content": "", "author": {"id": "101", "name": "alice"}},
+    {"id": "2", "type": "Default", "timestamp": "2024-03-01T10:01:00+00:00", "content": "hi <@102> and <@&201>, ask <@&209>", "author": {"id": "101", "name": "alice", "nickname": "Alice", "roles": [{"id": "201", "name": "mods"}]}, "mentions": [{"id": "102", "name": "bob"}], "reference": {"messageId": "1"}},
+    {"id": "3", "type": "Reply", "timestamp": "2024-03-01T10:02:00+00:00", "content": "**hello**", "author": {"id": "102", "name": "bob"}, "reference": {"messageId": "2"}},
+    {"id": "4", "type": "ChannelPinnedMessage", "timestamp": "2024-03-01T10:03:00+00:00", "content": "", "author": {"id": "102", "name": "bob"}, "reference": {"messageId": "3"}},
+    {"id": "5", "type": "RecipientAdd", "timestamp": "2024-03-01T10:04:00+00:00", "content": "", "author": {"id": "102", "name": "bob"}, "mentions": [{"id": "103", "name": "carol"}]},
+    {"id": "6", "type": "ChannelNameChange", "timestamp": "2024-03-01T10:05:00+00:00", "content": "", "author": {"id": "102", "name": "bob"}}
+  ]
+}`
+	path := filepath.Join(t.TempDir(), "general.json")
+	if err := os.WriteFile(path, []byte(export), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ImportDiscord(path, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"system  alice joined the server",
+		" Alice hi @bob and @mods, ask @role",
+		" bob hello ^2",
+		"system  bob pinned a message",
+		"system  bob added carol to the group",
+		"system  bob changed the channel name",
+	}
+	var got []string
+	for _, e := range entries {
+		s := string(e.Kind) + " " + e.User + " " + e.Message
+		if e.ParentID != "" {
+			s += " ^" + e.ParentID
+		}
+		got = append(got, s)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := ImportDiscord(path, ImportOptions{Channel: "#random"}); err == nil {
+		t.Error("another channel: no error")
+	}
+}
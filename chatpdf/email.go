@@ -0,0 +1,536 @@
+package chatpdf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// emailMessage is one raw message of a mailbox with where it came from
+type emailMessage struct {
+	path string // file holding the message
+	line int    // line of the message in an mbox file; 0 for .eml files
+	data []byte
+}
+
+// emailHeaders matches the start of a message saved as a file
+var emailHeaders = regexp.MustCompile(`^(?i)(Return-Path|Received|Delivered-To|Message-ID|MIME-Version|From|Date|Subject|To): `)
+
+// emailAttribution matches the line introducing a quoted reply, such as
+// "On Mon, 4 Mar 2024 at 10:00, Alice <alice@example.com> wrote:"
+var emailAttribution = regexp.MustCompile(`(?i)^(on .+ wrote|am .+ schrieb .+|le .+ a écrit)\s*:\s*$`)
+
+// emailReplyPrefix matches the reply and forward prefixes of subjects
+var emailReplyPrefix = regexp.MustCompile(`(?i)^((re|fwd?|aw|wg|sv|vs)(\[\d+\])?\s*:\s*)+`)
+
+// emailCutoffs start the quoted original below a reply in clients that do
+// not quote with ">", and common mobile signatures
+var emailCutoffs = []*regexp.Regexp{
+	regexp.MustCompile(`^-{2,}\s*(Original Message|Forwarded message)\s*-{2,}$`),
+	emailSeparator,
+	regexp.MustCompile(`^Sent from my .+$`),
+	regexp.MustCompile(`^Get Outlook for .+$`),
+}
+
+// emailSeparator is the line Outlook draws above the quoted original
+var emailSeparator = regexp.MustCompile(`^_{10,}$`)
+
+// The lines of the header block Outlook puts above the quoted original,
+// bold in messages converted from HTML
+var (
+	outlookFrom   = regexp.MustCompile(`^\*?From:\*? .+$`)
+	outlookSent   = regexp.MustCompile(`^\*?(Sent|Date):\*? .+$`)
+	outlookTo     = regexp.MustCompile(`^\*?(To|Subject):\*? .*$`)
+	outlookHeader = regexp.MustCompile(`^\*?[A-Z][A-Za-z-]*:\*?( |$)`)
+)
+
+// emailDecoder decodes encoded words in headers, in the charsets that
+// decodeCharset knows
+var emailDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		text, err := decodeCharset(charset, data)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(text), nil
+	},
+}
+
+// ImportEmail reads an email thread from an mbox file, a single .eml file
+// or a directory of .eml files, ordered by their Date headers. Quoted
+// replies and signatures are stripped, so each entry holds only what its
+// sender wrote; the subject is shown in bold when it changes. Plain text
+// parts are preferred over HTML, the usual charsets and transfer encodings
+// are decoded, and other parts are listed as attachments. Text in an unknown
+// charset is read as UTF-8 and attachments that cannot be decoded are
+// listed without their size, both reported through opts.Warn. Message-ID and
+// In-Reply-To give ID and ParentID. opts.Channel keeps only the messages
+// whose subject contains it.
+func ImportEmail(path string, opts ImportOptions) ([]ChatEntry, error) {
+	messages, err := readMailbox(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, pathError(path, errors.New("no email messages found"))
+	}
+
+	var entries []ChatEntry
+	var subjects []string
+	for _, raw := range messages {
+		entry, subject, err := emailEntry(raw.data, func(code WarningCode, err error) {
+			if raw.line > 0 {
+				err = &LineError{Line: raw.line, Err: err}
+			}
+			opts.warn(Warning{Code: code, Path: raw.path, Err: err})
+		})
+		if err != nil {
+			if raw.line > 0 {
+				err = &LineError{Line: raw.line, Err: err}
+			}
+			return nil, pathError(raw.path, err)
+		}
+		if opts.Channel != "" && !strings.Contains(strings.ToLower(subject), strings.ToLower(opts.Channel)) {
+			continue
+		}
+		if opts.inRange(entry.Timestamp) {
+			entries = append(entries, entry)
+			subjects = append(subjects, subject)
+		}
+	}
+
+	// Order by date, then show each subject where it changes
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return entries[order[i]].Timestamp.Before(entries[order[j]].Timestamp)
+	})
+	sorted := make([]ChatEntry, len(entries))
+	var last string
+	for i, k := range order {
+		sorted[i] = entries[k]
+		subject := emailReplyPrefix.ReplaceAllString(subjects[k], "")
+		if subject != "" && !strings.EqualFold(subject, last) {
+			addSubject(&sorted[i], subject)
+		}
+		last = subject
+	}
+	return sorted, nil
+}
+
+// readMailbox reads the raw messages of an mbox file, an .eml file or a
+// directory of .eml files
+func readMailbox(path string) ([]emailMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		files, err := filepath.Glob(filepath.Join(path, "*.eml"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		var messages []emailMessage
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, emailMessage{path: file, data: data})
+		}
+		return messages, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte("From ")) {
+		return []emailMessage{{path: path, data: data}}, nil
+	}
+	return splitMbox(path, data), nil
+}
+
+// splitMbox splits an mbox file at its "From " lines, undoing the ">From "
+// quoting of lines in message bodies
+func splitMbox(path string, data []byte) []emailMessage {
+	var messages []emailMessage
+	var cur *bytes.Buffer
+	var start int
+	flush := func() {
+		if cur != nil {
+			messages = append(messages, emailMessage{path: path, line: start, data: cur.Bytes()})
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	prevBlank := true
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if prevBlank && bytes.HasPrefix(line, []byte("From ")) {
+			flush()
+			cur, start = new(bytes.Buffer), lineNo+1
+			prevBlank = false
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+			line = line[1:]
+		}
+		cur.Write(line)
+		cur.WriteByte('\n')
+		prevBlank = len(bytes.TrimRight(line, "\r")) == 0
+	}
+	flush()
+	return messages
+}
+
+// emailEntry converts a raw message to a chat entry, returning its decoded
+// subject separately. Problems with single parts are passed to warn.
+func emailEntry(data []byte, warn func(WarningCode, error)) (ChatEntry, string, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return ChatEntry{}, "", err
+	}
+
+	var entry ChatEntry
+	entry.Timestamp, err = msg.Header.Date()
+	if err != nil {
+		return ChatEntry{}, "", fmt.Errorf("invalid Date header %q", msg.Header.Get("Date"))
+	}
+
+	parser := mail.AddressParser{WordDecoder: emailDecoder}
+	if from, err := parser.Parse(msg.Header.Get("From")); err == nil {
+		entry.User = from.Name
+		if entry.User == "" {
+			entry.User = from.Address
+		}
+	} else {
+		entry.User = decodeHeader(msg.Header.Get("From"))
+	}
+	entry.ID = messageID(msg.Header.Get("Message-ID"))
+	entry.ParentID = messageID(msg.Header.Get("In-Reply-To"))
+
+	body := emailBody{warn: warn}
+	if err := body.readPart(msg.Header, msg.Body); err != nil {
+		return ChatEntry{}, "", err
+	}
+	entry.Message, entry.Spans = body.text()
+	entry.Attachments = body.attachments
+	return entry, decodeHeader(msg.Header.Get("Subject")), nil
+}
+
+// decodeHeader decodes the encoded words of a header value. Raw 8-bit
+// headers that are not UTF-8 are read as Windows-1252.
+func decodeHeader(s string) string {
+	if !utf8.ValidString(s) {
+		s, _ = charmap.Windows1252.NewDecoder().String(s)
+	}
+	decoded, err := emailDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// messageID returns the first message ID of a header, without its brackets
+func messageID(s string) string {
+	if start := strings.IndexByte(s, '<'); start >= 0 {
+		if end := strings.IndexByte(s[start:], '>'); end > 0 {
+			return s[start+1 : start+end]
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// addSubject puts the subject in bold on the first line of the entry
+func addSubject(entry *ChatEntry, subject string) {
+	prefix := subject + "\n"
+	if entry.Message == "" {
+		prefix = subject
+	}
+	for i := range entry.Spans {
+		entry.Spans[i].Start += len(prefix)
+		entry.Spans[i].End += len(prefix)
+	}
+	entry.Spans = append([]Span{{End: len(subject), Style: StyleBold}}, entry.Spans...)
+	entry.Message = prefix + entry.Message
+}
+
+// emailBody collects the text and attachments of a message's MIME parts
+type emailBody struct {
+	plain, html string
+	attachments []Attachment
+	warn        func(WarningCode, error) // called for parts that cannot be read fully
+}
+
+// readPart reads a MIME part, recursing into multiparts. The first plain
+// text and first HTML part that are not attachments form the body. Only
+// the body failing to decode is an error; attachments that fail are listed
+// without their size.
+func (b *emailBody) readPart(header map[string][]string, r io.Reader) error {
+	get := func(key string) string {
+		if v := header[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	mediaType, params, err := mime.ParseMediaType(get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", mediaType, err)
+			}
+			if err := b.readPart(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(get("Content-Disposition"))
+	name := dparams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	name = decodeHeader(name)
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	data, err := decodeTransfer(get("Content-Transfer-Encoding"), r)
+
+	if disposition == "attachment" || !isText || (name != "" && disposition != "inline") {
+		if name == "" {
+			name = "Attachment"
+		}
+		if err != nil {
+			// Listed without a size, since data is nil
+			b.warnf(WarnAttachmentInvalid, fmt.Errorf("attachment %q: %w", name, err))
+		}
+		b.attachments = append(b.attachments, Attachment{Name: name, MimeType: mediaType, Size: int64(len(data))})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	text, err := decodeCharset(params["charset"], data)
+	if err != nil {
+		// Read unknown charsets as UTF-8 rather than losing the message
+		text = strings.ToValidUTF8(string(data), "\uFFFD")
+		b.warnf(WarnCharsetUnknown, err)
+	}
+	switch {
+	case mediaType == "text/plain" && b.plain == "":
+		b.plain = text
+	case mediaType == "text/html" && b.html == "":
+		b.html = text
+	}
+	return nil
+}
+
+// warnf reports a problem with a part through b.warn, if set
+func (b *emailBody) warnf(code WarningCode, err error) {
+	if b.warn != nil {
+		b.warn(code, err)
+	}
+}
+
+// text returns the body without quoted replies and signatures
+func (b *emailBody) text() (string, []Span) {
+	if b.plain != "" || b.html == "" {
+		return stripReply(b.plain), nil
+	}
+
+	// Quotes in HTML mail are blockquotes, or divs in Gmail's case
+	root := parseHTML(b.html)
+	for _, quote := range root.findAll(func(n *htmlNode) bool {
+		return n.tag == "blockquote" || n.hasClass("gmail_quote", "gmail_signature", "moz-signature")
+	}) {
+		quote.remove()
+	}
+	text, spans := nodeText(root)
+	stripped := stripReply(text)
+	if !strings.HasPrefix(text, stripped) {
+		// Quotes were dropped from the middle; the spans no longer fit
+		return stripped, nil
+	}
+	var kept []Span
+	for _, s := range spans {
+		if s.Start < len(stripped) {
+			s.End = clampOffset(s.End, stripped)
+			kept = append(kept, s)
+		}
+	}
+	return stripped, kept
+}
+
+// stripReply cuts a message body at its signature or the original message
+// quoted below it, and drops lines quoted with ">" along with the line
+// introducing them.
+func stripReply(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	var kept []string
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed == "--" {
+			break
+		}
+		if len(kept) > 0 && (emailCutoff(trimmed) || outlookQuote(lines, i)) {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			// Drop the attribution line and blank lines before the quote
+			for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+				kept = kept[:len(kept)-1]
+			}
+			if len(kept) > 0 && emailAttribution.MatchString(strings.TrimSpace(kept[len(kept)-1])) {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	// A trailing attribution line whose quote was cut off
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+	if len(kept) > 0 && emailAttribution.MatchString(strings.TrimSpace(kept[len(kept)-1])) {
+		kept = kept[:len(kept)-1]
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// emailCutoff reports whether a line starts the original message quoted
+// below a reply or a mobile signature
+func emailCutoff(line string) bool {
+	for _, re := range emailCutoffs {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// outlookQuote reports whether lines[i] is the From line of the header
+// block Outlook puts above the quoted original: it follows a separator
+// line, or Sent or Date and To or Subject lines follow it
+func outlookQuote(lines []string, i int) bool {
+	if !outlookFrom.MatchString(strings.TrimSpace(lines[i])) {
+		return false
+	}
+	for j := i - 1; j >= 0; j-- {
+		if prev := strings.TrimSpace(lines[j]); prev != "" {
+			if emailSeparator.MatchString(prev) {
+				return true
+			}
+			break
+		}
+	}
+	var sent, to bool
+	for _, next := range lines[i+1:] {
+		next = strings.TrimSpace(next)
+		if !outlookHeader.MatchString(next) {
+			break
+		}
+		sent = sent || outlookSent.MatchString(next)
+		to = to || outlookTo.MatchString(next)
+	}
+	return sent && to
+}
+
+// decodeTransfer undoes a part's Content-Transfer-Encoding
+func decodeTransfer(encoding string, r io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		// Line breaks and padding problems are common in the wild
+		clean := bytes.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, data)
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(clean)))
+		n, err := base64.StdEncoding.Decode(decoded, clean)
+		if err != nil {
+			n, err = base64.RawStdEncoding.Decode(decoded, bytes.TrimRight(clean, "="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 part: %w", err)
+			}
+		}
+		return decoded[:n], nil
+	case "quoted-printable":
+		data, err := io.ReadAll(quotedprintable.NewReader(r))
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted-printable part: %w", err)
+		}
+		return data, nil
+	}
+	return io.ReadAll(r)
+}
+
+// decodeCharset converts text in the named charset to UTF-8. Charsets are
+// looked up by their WHATWG names first, so ISO 8859-1 is read as
+// Windows-1252 as mail clients do, then by their IANA names.
+func decodeCharset(charset string, data []byte) (string, error) {
+	name := strings.ToLower(strings.Trim(charset, `" `))
+	switch name {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return strings.ToValidUTF8(string(data), "\uFFFD"), nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		enc, err = ianaindex.MIME.Encoding(name)
+	}
+	// htmlindex maps charsets that cannot be decoded safely, such as
+	// ISO-2022-KR, to the replacement encoding
+	if err != nil || enc == nil || enc == encoding.Replacement {
+		return "", fmt.Errorf("unknown charset %q", charset)
+	}
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", charset, err)
+	}
+	return strings.ToValidUTF8(string(text), "\uFFFD"), nil
+}
@@ -0,0 +1,208 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStripReply(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "quoted reply with attribution",
+			text: "Sounds good.\n\nOn Mon, 4 Mar 2024 at 10:00, Alice <alice@example.com> wrote:\n> Lunch at noon?\n> Alice",
+			want: "Sounds good.",
+		},
+		{
+			name: "inline replies keep the answers",
+			text: "> Lunch?\nYes.\n> Where?\nThe usual place.",
+			want: "Yes.\nThe usual place.",
+		},
+		{
+			name: "attribution line without a quote below",
+			text: "Thanks!\n\nAm 04.03.2024 um 10:00 schrieb Alice:",
+			want: "Thanks!",
+		},
+		{
+			name: "signature",
+			text: "See you.\n-- \nBob\nACME Inc.",
+			want: "See you.",
+		},
+		{
+			name: "mobile signature",
+			text: "On my way\n\nSent from my iPhone",
+			want: "On my way",
+		},
+		{
+			name: "original message separator",
+			text: "Done.\n\n-----Original Message-----\nFrom: Alice\nPlease do it.",
+			want: "Done.",
+		},
+		{
+			name: "Outlook header block",
+			text: "Done.\n\nFrom: Alice <alice@example.com>\nSent: Monday, March 4, 2024 10:00 AM\nTo: Bob <bob@example.com>\nSubject: Task\n\nPlease do it.",
+			want: "Done.",
+		},
+		{
+			name: "Outlook header block after a separator",
+			text: "Done.\n\n________________________________\nFrom: Alice <alice@example.com>\nPlease do it.",
+			want: "Done.",
+		},
+		{
+			name: "bold Outlook header block from HTML",
+			text: "Done.\n\n*From:* Alice\n*Date:* Monday, March 4, 2024\n*Subject:* Task\n\nPlease do it.",
+			want: "Done.",
+		},
+		{
+			name: "From line in the text",
+			text: "Forwarding the numbers.\nFrom: the finance team, with thanks.\nTo: be read before Friday.",
+			want: "Forwarding the numbers.\nFrom: the finance team, with thanks.\nTo: be read before Friday.",
+		},
+		{
+			name: "From line without the rest of the header block",
+			text: "Hi,\nFrom: Alice\nSubject: Task\nare fields of the form.",
+			want: "Hi,\nFrom: Alice\nSubject: Task\nare fields of the form.",
+		},
+		{
+			name: "cutoff on the first line is kept",
+			text: "Sent from my phone, sorry for typos\nok",
+			want: "Sent from my phone, sorry for typos\nok",
+		},
+		{
+			name: "CRLF line endings",
+			text: "Yes.\r\n\r\n> Lunch?\r\n",
+			want: "Yes.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripReply(tt.text); got != tt.want {
+				t.Errorf("stripReply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	tests := []struct {
+		charset string
+		data    string
+		want    string
+		err     bool
+	}{
+		{"", "plain", "plain", false},
+		{"UTF-8", "caf\xc3\xa9", "café", false},
+		{"us-ascii", "bad \xff", "bad �", false},
+		{"ISO-8859-1", "caf\xe9 \x93quoted\x94", "café “quoted”", false},
+		{"\"iso-8859-15\"", "\xa4 5", "€ 5", false},
+		{"windows-1251", "\xcf\xf0\xe8\xe2\xe5\xf2", "Привет", false},
+		{"KOI8-R", "\xf0\xd2\xc9\xd7\xc5\xd4", "Привет", false},
+		{"Shift_JIS", "\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd", "こんにちは", false},
+		{"x-unknown", "text", "", true},
+		{"ISO-2022-KR", "text", "", true},
+	}
+	for _, tt := range tests {
+		got, err := decodeCharset(tt.charset, []byte(tt.data))
+		if (err != nil) != tt.err {
+			t.Errorf("decodeCharset(%q) error = %v, want error %v", tt.charset, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("decodeCharset(%q) = %q, want %q", tt.charset, got, tt.want)
+		}
+	}
+}
+
+func TestImportEmailUnknownCharset(t *testing.T) {
+	dir := t.TempDir()
+	eml := "From: Alice <alice@example.com>\r\n" +
+		"Date: Mon, 4 Mar 2024 10:00:00 +0000\r\n" +
+		"Subject: =?koi8-r?B?8NLJ18XU?=\r\n" +
+		"Content-Type: text/plain; charset=x-unknown\r\n" +
+		"\r\n" +
+		"hello\r\n"
+	path := filepath.Join(dir, "a.eml")
+	if err := os.WriteFile(path, []byte(eml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []Warning
+	entries, err := ImportEmail(path, ImportOptions{Warn: func(w Warning) { warnings = append(warnings, w) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Message != "Привет\nhello" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarnCharsetUnknown || warnings[0].Path != path {
+		t.Errorf("warnings = %v, want one %s warning for %s", warnings, WarnCharsetUnknown, path)
+	}
+}
+
+func TestImportEmailCorruptAttachment(t *testing.T) {
+	multipart := func(attachmentEncoding, attachment, bodyEncoding, body string) string {
+		return "From: Alice <alice@example.com>\r\n" +
+			"Date: Mon, 4 Mar 2024 10:00:00 +0000\r\n" +
+			"Subject: Report\r\n" +
+			"Content-Type: multipart/mixed; boundary=b\r\n" +
+			"\r\n" +
+			"--b\r\n" +
+			"Content-Type: text/plain\r\n" +
+			"Content-Transfer-Encoding: " + bodyEncoding + "\r\n" +
+			"\r\n" +
+			body + "\r\n" +
+			"--b\r\n" +
+			"Content-Type: application/pdf; name=report.pdf\r\n" +
+			"Content-Disposition: attachment; filename=report.pdf\r\n" +
+			"Content-Transfer-Encoding: " + attachmentEncoding + "\r\n" +
+			"\r\n" +
+			attachment + "\r\n" +
+			"--b--\r\n"
+	}
+	tests := []struct {
+		name     string
+		eml      string
+		warnings int
+		err      bool
+	}{
+		{"valid attachment", multipart("base64", "JVBERi0=", "7bit", "See attached."), 0, false},
+		{"corrupt base64 attachment", multipart("base64", "JVB!!ERi0", "7bit", "See attached."), 1, false},
+		{"corrupt quoted-printable attachment", multipart("quoted-printable", "bad \x01 byte", "7bit", "See attached."), 1, false},
+		{"corrupt body", multipart("base64", "JVBERi0=", "base64", "U2V!!lIGF0"), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "a.eml")
+			if err := os.WriteFile(path, []byte(tt.eml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var warnings []Warning
+			entries, err := ImportEmail(path, ImportOptions{Warn: func(w Warning) { warnings = append(warnings, w) }})
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if len(entries) != 1 || entries[0].Message != "Report\nSee attached." || len(entries[0].Attachments) != 1 {
+				t.Fatalf("entries = %+v", entries)
+			}
+			a := entries[0].Attachments[0]
+			if a.Name != "report.pdf" || (a.Size == 0) != (tt.warnings > 0) {
+				t.Errorf("attachment = %+v", a)
+			}
+			if len(warnings) != tt.warnings {
+				t.Fatalf("warnings = %v, want %d", warnings, tt.warnings)
+			}
+			for _, w := range warnings {
+				if w.Code != WarnAttachmentInvalid || w.Path != path {
+					t.Errorf("warning = %+v, want %s for %s", w, WarnAttachmentInvalid, path)
+				}
+			}
+		})
+	}
+}
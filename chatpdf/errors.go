@@ -0,0 +1,100 @@
+package chatpdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OptionError reports an option with an unsupported value
+type OptionError struct {
+	Option string   // option name, e.g. "Theme"
+	Value  string   // the rejected value
+	Valid  []string // the accepted values
+}
+
+func (e *OptionError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("no %s given (available: %s)", optionLabels[e.Option], strings.Join(e.Valid, ", "))
+	}
+	return fmt.Sprintf("unknown %s %q (available: %s)", optionLabels[e.Option], e.Value, strings.Join(e.Valid, ", "))
+}
+
+// optionLabels names Options fields in error messages
+var optionLabels = map[string]string{
+	"PageSize":     "page size",
+	"Theme":        "theme",
+	"ColorMode":    "color mode",
+	"Layout":       "layout",
+	"Threads":      "thread mode",
+	"CodeOverflow": "code overflow",
+	"Backend":      "backend",
+	"Format":       "input format",
+	"Channel":      "channel",
+	"Preset":       "log preset",
+	"Column":       "column",
+	"Field":        "column field",
+}
+
+// LineError reports a malformed record in a chat log
+type LineError struct {
+	Line int // 1-based line number in the input
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// WarningCode identifies the kind of a Warning
+type WarningCode string
+
+// Warning codes
+const (
+	WarnFontMissing       WarningCode = "font-missing"       // a font file does not exist; a fallback font is used
+	WarnFontInvalid       WarningCode = "font-invalid"       // a font file could not be loaded; a fallback font is used
+	WarnEmojiMissing      WarningCode = "emoji-missing"      // an emoji image does not exist; the emoji is drawn as text
+	WarnAvatarMissing     WarningCode = "avatar-missing"     // an avatar image does not exist; initials are drawn instead
+	WarnAvatarInvalid     WarningCode = "avatar-invalid"     // an avatar image could not be decoded; initials are drawn instead
+	WarnImageMissing      WarningCode = "image-missing"      // an image attachment does not exist; it is listed by name instead
+	WarnImageInvalid      WarningCode = "image-invalid"      // an image attachment could not be decoded; it is listed by name instead
+	WarnCharsetUnknown    WarningCode = "charset-unknown"    // imported text is in an unknown charset; it is read as UTF-8
+	WarnAttachmentInvalid WarningCode = "attachment-invalid" // an imported attachment could not be decoded; it is listed without its size
+)
+
+// Warning describes a problem that did not stop the document from rendering
+type Warning struct {
+	Code WarningCode
+	Path string // the file the warning is about
+	Err  error  // the underlying error, if any
+}
+
+func (w Warning) String() string {
+	switch w.Code {
+	case WarnFontMissing:
+		return fmt.Sprintf("font file not found: %s", w.Path)
+	case WarnFontInvalid:
+		return fmt.Sprintf("could not load font %s: %v", w.Path, w.Err)
+	case WarnEmojiMissing:
+		return fmt.Sprintf("emoji image not found: %s", w.Path)
+	case WarnAvatarMissing:
+		return fmt.Sprintf("avatar image not found: %s", w.Path)
+	case WarnAvatarInvalid:
+		return fmt.Sprintf("could not load avatar image %s: %v", w.Path, w.Err)
+	case WarnImageMissing:
+		return fmt.Sprintf("attached image not found: %s", w.Path)
+	case WarnImageInvalid:
+		return fmt.Sprintf("could not load attached image %s: %v", w.Path, w.Err)
+	case WarnCharsetUnknown:
+		return fmt.Sprintf("%s: %v; text read as UTF-8", w.Path, w.Err)
+	case WarnAttachmentInvalid:
+		return fmt.Sprintf("%s: %v; listed without its size", w.Path, w.Err)
+	}
+	if w.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", w.Code, w.Path, w.Err)
+	}
+	return fmt.Sprintf("%s: %s", w.Code, w.Path)
+}
@@ -0,0 +1,91 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultFontPath is the bundled TrueType font used when no font is configured
+const DefaultFontPath = "fonts/DejaVuSans.ttf"
+
+// DefaultMonoFontPath is the bundled monospace font used for code
+const DefaultMonoFontPath = "fonts/DejaVuSansMono.ttf"
+
+// fontStyleSuffixes lists the file name suffixes tried for each style
+// variant of a TrueType font, e.g. DejaVuSans-Bold.ttf for "B"
+var fontStyleSuffixes = map[string][]string{
+	"B":  {"-Bold", "Bold", "-bold"},
+	"I":  {"-Oblique", "-Italic", "Italic", "-italic"},
+	"BI": {"-BoldOblique", "-BoldItalic", "BoldItalic", "-bolditalic"},
+}
+
+// registerFonts registers the UTF-8 TrueType text and monospace fonts. When
+// the text font cannot be loaded, the backend's built-in font is used
+// instead; when the monospace font cannot, code is set in the text font.
+func (g *PDFGenerator) registerFonts(path, monoPath string) {
+	if path == "" {
+		path = DefaultFontPath
+	}
+	if monoPath == "" {
+		monoPath = DefaultMonoFontPath
+	}
+	g.fontFamily = g.registerFamily(path)
+	if g.fontFamily != "" {
+		g.monoFamily = g.registerFamily(monoPath)
+	}
+	if g.monoFamily == "" {
+		g.monoFamily = g.fontFamily
+	}
+}
+
+// registerFamily registers the font at path in all four styles and returns
+// its family name, or "" when it cannot be loaded. Style variants are looked
+// up next to the regular font file and fall back to the regular face when
+// missing.
+func (g *PDFGenerator) registerFamily(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		g.warn(Warning{Code: WarnFontMissing, Path: path, Err: err})
+		return ""
+	}
+
+	family := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if err := g.backend.AddFont(family, "", path); err != nil {
+		g.warn(Warning{Code: WarnFontInvalid, Path: path, Err: err})
+		return ""
+	}
+	for style := range fontStyleSuffixes {
+		variant := fontVariantPath(path, style)
+		if variant == "" || g.backend.AddFont(family, style, variant) != nil {
+			g.backend.AddFont(family, style, path)
+		}
+	}
+	return family
+}
+
+// fontVariantPath returns the first existing style variant of the font at
+// path, or "" when there is none
+func fontVariantPath(path, style string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for _, suffix := range fontStyleSuffixes[style] {
+		candidate := base + suffix + ext
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// setFont selects the document font in the given style and size; an empty
+// family selects the backend's built-in font
+func (g *PDFGenerator) setFont(style string, size float64) {
+	g.fontStyle, g.fontSize = style, size
+	g.backend.SetFont(g.fontFamily, style, size)
+}
+
+// setTextColor sets the color of text drawn from now on
+func (g *PDFGenerator) setTextColor(c Color) {
+	g.textColor = c
+	g.backend.SetTextColor(c)
+}
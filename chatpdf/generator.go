@@ -0,0 +1,669 @@
+// Package chatpdf renders chat logs into styled PDF documents.
+package chatpdf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChatEntry represents a single chat message
+type ChatEntry struct {
+	Timestamp time.Time
+	User      string
+	Message   string
+	Spans     []Span // formatting of parts of Message
+	R         int
+	G         int
+	B         int
+	IconPath  string
+
+	// Kind distinguishes events such as members joining from messages
+	Kind EntryKind
+
+	// ID identifies the message within its source; ParentID is the ID of the
+	// message it replies to in a thread
+	ID       string
+	ParentID string
+
+	Attachments []Attachment
+	Reactions   []Reaction
+
+	// Emoji maps text such as a custom emoji's :name: to an image drawn in
+	// its place, in addition to the document's emoji images
+	Emoji map[string]string
+}
+
+// EntryKind is the kind of a chat entry
+type EntryKind string
+
+// Entry kinds
+const (
+	KindMessage EntryKind = ""       // a message written by User
+	KindSystem  EntryKind = "system" // a notice from the chat service, drawn as centered small print
+	KindAction  EntryKind = "action" // an IRC style "/me" action, drawn in italics after the user's name
+)
+
+// Attachment is a file sent with a message
+type Attachment struct {
+	Name     string
+	Path     string // local copy of the file, if any
+	URL      string // remote location of the file, if any
+	MimeType string
+	Size     int64 // in bytes; 0 when unknown
+}
+
+// Reaction is an emoji reaction to a message
+type Reaction struct {
+	Emoji string // the emoji itself, or its :name: when there is no Unicode form
+	Count int
+}
+
+// emojiToImage maps emoji characters to their default image paths
+var emojiToImage = map[string]string{
+	"✅": "./images/check.png",
+	"❌": "./images/close.png",
+	"📚": "./images/book.png",
+	"🎯": "./images/target.png",
+	"🚀": "./images/rocket.png",
+}
+
+// DefaultEmojiImages returns a copy of the default emoji to image mapping,
+// with paths relative to the working directory
+func DefaultEmojiImages() map[string]string {
+	images := make(map[string]string, len(emojiToImage))
+	for emoji, path := range emojiToImage {
+		images[emoji] = path
+	}
+	return images
+}
+
+// pageSizes lists the supported page size names
+var pageSizes = []string{"A3", "A4", "A5", "Letter", "Legal"}
+
+// Options configures a PDFGenerator
+type Options struct {
+	Title    string // document title shown in the header
+	LogoPath string // header logo; no logo is drawn when empty or missing
+	PageSize string // one of pageSizes; defaults to A4
+	Theme    string // built-in theme name; defaults to "light"
+	FontPath string // UTF-8 TrueType font; defaults to DefaultFontPath
+
+	// MonoFontPath is the UTF-8 TrueType font for code; defaults to
+	// DefaultMonoFontPath
+	MonoFontPath string
+
+	// EmojiImages maps emoji to PNG images drawn in their place; defaults to
+	// DefaultEmojiImages
+	EmojiImages map[string]string
+
+	// ColorMode selects where ChatEntry colors are drawn; defaults to ColorText
+	ColorMode ColorMode
+
+	// HideAvatars drops the avatar column drawn beside each entry
+	HideAvatars bool
+
+	// Layout arranges the entries; defaults to LayoutClassic. With
+	// LayoutBubbles, SelfUser's messages are drawn on the right.
+	Layout   Layout
+	SelfUser string
+
+	// Threads places replies, using the entries' ID and ParentID; defaults
+	// to ThreadFlat
+	Threads ThreadMode
+
+	// Markdown formats the Markdown in messages that have no Spans of
+	// their own; otherwise the markers are printed as they are
+	Markdown bool
+
+	// CodeOverflow selects how code lines wider than their block are
+	// drawn; defaults to CodeWrap. CodeLineNumbers numbers the lines of
+	// code blocks.
+	CodeOverflow    CodeOverflow
+	CodeLineNumbers bool
+
+	// FooterURL is linked from the footer of every page; no link when empty
+	FooterURL string
+
+	// Backend names the drawing library: "gofpdf" (default) or "gopdf"
+	Backend string
+}
+
+// DefaultFooterURL is the footer link used by NewPDFGenerator
+const DefaultFooterURL = "https://chiphub.com"
+
+// PDFGenerator handles PDF document creation and styling
+type PDFGenerator struct {
+	backend      Backend
+	title        string
+	theme        Theme
+	colorMode    ColorMode
+	pageLayout   Layout
+	selfUser     string
+	threads      ThreadMode
+	markdown     bool
+	codeOverflow CodeOverflow
+	fontFamily   string
+	monoFamily   string
+	fontStyle    string
+	fontSize     float64
+	textColor    Color
+	entries      []ChatEntry
+	y            float64
+	margin       float64
+	pageWidth    float64
+	pageHeight   float64
+	logoPath     string
+	footerURL    string
+	generatedAt  time.Time
+	totalPages   string
+	headerHeight float64
+	footerHeight float64
+	emojiImages  map[string]string
+	hideAvatars  bool
+	lineNumbers  bool
+	avatars      map[string]*loadedImage
+	images       map[string]*loadedImage
+	warnings     []Warning
+	rendered     bool
+}
+
+// checkImages collects the emoji whose images exist; the rest are drawn as text
+func (g *PDFGenerator) checkImages(images map[string]string) {
+	if images == nil {
+		images = emojiToImage
+	}
+	// Check in a stable order so warnings come out the same every run
+	emojis := make([]string, 0, len(images))
+	for emoji := range images {
+		emojis = append(emojis, emoji)
+	}
+	sort.Strings(emojis)
+
+	g.emojiImages = make(map[string]string, len(images))
+	for _, emoji := range emojis {
+		path := images[emoji]
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			g.warn(Warning{Code: WarnEmojiMissing, Path: path, Err: err})
+			continue
+		}
+		g.emojiImages[emoji] = path
+	}
+}
+
+// NewPDFGenerator creates a new PDF document with default settings
+func NewPDFGenerator(title string) *PDFGenerator {
+	generator, err := NewPDFGeneratorWithOptions(Options{
+		Title:     title,
+		LogoPath:  "logo.png",
+		FooterURL: DefaultFooterURL,
+	})
+	if err != nil {
+		// The default options are always valid
+		panic(err)
+	}
+	return generator
+}
+
+// NewPDFGeneratorWithOptions creates a new PDF document configured by opts
+func NewPDFGeneratorWithOptions(opts Options) (*PDFGenerator, error) {
+	pageSize, err := lookupPageSize(opts.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	theme, err := lookupTheme(opts.Theme)
+	if err != nil {
+		return nil, err
+	}
+	colorMode, err := lookupColorMode(opts.ColorMode)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := lookupLayout(opts.Layout)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := lookupThreadMode(opts.Threads)
+	if err != nil {
+		return nil, err
+	}
+	codeOverflow, err := lookupCodeOverflow(opts.CodeOverflow)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := newBackend(opts.Backend, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pageWidth, pageHeight := backend.PageSize()
+	margin := 20.0
+
+	generator := &PDFGenerator{
+		backend:      backend,
+		title:        opts.Title,
+		theme:        theme,
+		colorMode:    colorMode,
+		pageLayout:   layout,
+		selfUser:     opts.SelfUser,
+		threads:      threads,
+		markdown:     opts.Markdown,
+		codeOverflow: codeOverflow,
+		logoPath:     opts.LogoPath,
+		footerURL:    opts.FooterURL,
+		margin:       margin,
+		pageWidth:    pageWidth,
+		pageHeight:   pageHeight,
+		headerHeight: 40.0,
+		footerHeight: 20.0,
+		hideAvatars:  opts.HideAvatars,
+		lineNumbers:  opts.CodeLineNumbers,
+		avatars:      make(map[string]*loadedImage),
+		images:       make(map[string]*loadedImage),
+	}
+
+	generator.registerFonts(opts.FontPath, opts.MonoFontPath)
+
+	// Draw the header and footer from the page hooks so every page gets them
+	backend.SetPageHooks(generator.addHeader, generator.addFooter)
+
+	// Check if images exist
+	generator.checkImages(opts.EmojiImages)
+
+	return generator, nil
+}
+
+// PageSizes returns the supported page size names
+func PageSizes() []string {
+	return append([]string(nil), pageSizes...)
+}
+
+// lookupPageSize resolves a page size name case-insensitively, defaulting to A4
+func lookupPageSize(name string) (string, error) {
+	if name == "" {
+		return "A4", nil
+	}
+	for _, size := range pageSizes {
+		if strings.EqualFold(size, name) {
+			return size, nil
+		}
+	}
+	return "", &OptionError{Option: "PageSize", Value: name, Valid: PageSizes()}
+}
+
+// AddChatEntry adds a chat entry to the document
+func (g *PDFGenerator) AddChatEntry(entry ChatEntry) {
+	if g.markdown && len(entry.Spans) == 0 && entry.Kind != KindSystem {
+		entry.Message, entry.Spans = markdownText(entry.Message, markdownCommon)
+	}
+
+	// Store the entry
+	g.entries = append(g.entries, entry)
+}
+
+func (g *PDFGenerator) drawBackground() {
+	g.backend.SetFillColor(g.theme.Background)
+	g.backend.Rect(0, 0, g.pageWidth, g.pageHeight, "F")
+}
+
+// addHeader draws the page background, logo, title and separator line
+func (g *PDFGenerator) addHeader() {
+	if !g.theme.Background.isWhite() {
+		g.drawBackground()
+	}
+
+	// Add logo if exists
+	if _, err := os.Stat(g.logoPath); g.logoPath != "" && err == nil {
+		g.backend.Image(g.logoPath, g.margin, g.margin, 30, 30)
+	}
+
+	// Add title
+	g.setFont("B", 24)
+	g.setTextColor(g.theme.Title)
+	g.backend.Text(g.margin+35, g.margin+23, g.title)
+
+	// Add separator line
+	ruleY := g.contentTop() - 6
+	g.backend.SetDrawColor(g.theme.Rule)
+	g.backend.SetLineWidth(0.3)
+	g.backend.Line(g.margin, ruleY, g.pageWidth-g.margin, ruleY)
+}
+
+// addFooter draws the separator line, generation time, link and page number
+func (g *PDFGenerator) addFooter() {
+	ruleY := g.contentBottom() + 4
+	g.backend.SetDrawColor(g.theme.Rule)
+	g.backend.SetLineWidth(0.3)
+	g.backend.Line(g.margin, ruleY, g.pageWidth-g.margin, ruleY)
+
+	baseline := ruleY + 6
+	g.setFont("I", 8)
+
+	// Add generation time on the left
+	g.setTextColor(g.theme.Footer)
+	g.backend.Text(g.margin, baseline, fmt.Sprintf("Generated on %s", g.generatedAt.Format("2006-01-02 15:04:05")))
+
+	// Add page number on the right. An alias is only replaced on output, so
+	// measure it as a two-digit total.
+	pageText := fmt.Sprintf("Page %d of %s", g.backend.PageNo(), g.totalPages)
+	pageWidth := g.stringWidth(strings.Replace(pageText, g.backend.TotalPagesAlias(), "00", 1))
+	g.backend.Text(g.pageWidth-g.margin-pageWidth, baseline, pageText)
+
+	// Add hyperlink in the middle
+	if g.footerURL != "" {
+		label := strings.TrimPrefix(strings.TrimPrefix(g.footerURL, "https://"), "http://")
+		w := g.stringWidth(label)
+		x := (g.pageWidth - w) / 2
+		g.setTextColor(g.theme.Link)
+		g.backend.Text(x, baseline, label)
+		g.backend.Link(x, baseline-3, w, 4, g.footerURL)
+	}
+}
+
+// contentTop is the Y position where content starts below the header
+func (g *PDFGenerator) contentTop() float64 {
+	return g.margin + g.headerHeight
+}
+
+// contentBottom is the Y position where content must stop above the footer
+func (g *PDFGenerator) contentBottom() float64 {
+	return g.pageHeight - g.footerHeight
+}
+
+// newPage starts a new page and moves to the top of the content area; the
+// header and footer are drawn by the page hooks
+func (g *PDFGenerator) newPage() {
+	// The page hooks change the font and color, and the backend restores
+	// them afterwards; keep the generator's copy in step
+	style, size, color := g.fontStyle, g.fontSize, g.textColor
+	g.backend.AddPage()
+	g.fontStyle, g.fontSize, g.textColor = style, size, color
+	g.y = g.contentTop()
+}
+
+func (g *PDFGenerator) addText(text string, x, y float64, fontSize float64) {
+	g.setFont("", fontSize)
+	g.backend.Text(x, y, text)
+}
+
+// warn records a problem that does not stop rendering
+func (g *PDFGenerator) warn(w Warning) {
+	g.warnings = append(g.warnings, w)
+}
+
+// Warnings returns the problems found so far. Font and emoji image problems
+// are known once the generator is created; avatar and attached image
+// problems once the document has been rendered by GeneratePDF or Output.
+func (g *PDFGenerator) Warnings() []Warning {
+	return append([]Warning(nil), g.warnings...)
+}
+
+// GeneratePDF creates the PDF document and writes it to filename. The file is
+// removed again when rendering fails.
+func (g *PDFGenerator) GeneratePDF(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := g.Output(f); err != nil {
+		f.Close()
+		os.Remove(filename)
+		return err
+	}
+	return f.Close()
+}
+
+// Output creates the PDF document and writes it to w. The document is laid
+// out on the first call; later calls write the same document again.
+func (g *PDFGenerator) Output(w io.Writer) error {
+	if !g.rendered {
+		g.render()
+		g.rendered = true
+	}
+	if err := g.backend.Output(w); err != nil {
+		return fmt.Errorf("writing PDF: %w", err)
+	}
+	return nil
+}
+
+// addMessage draws the entry's message text in a column of the given width,
+// applying the entry color according to the color mode
+func (g *PDFGenerator) addMessage(entry ChatEntry, x, width float64) {
+	const fontSize = 12
+
+	color, hasColor := entryColor(entry)
+	text := g.theme.Text
+	var pad float64
+	var decorate decoration
+
+	switch g.colorMode {
+	case ColorText:
+		if hasColor {
+			text = color
+		}
+	case ColorBar:
+		if hasColor {
+			const barWidth, barGap = 1.2, 3.0
+			decorate = func(top, height float64) {
+				g.backend.SetFillColor(color)
+				g.backend.Rect(x, top, barWidth, height, "F")
+			}
+			x += barWidth + barGap
+			width -= barWidth + barGap
+		}
+	case ColorBubble:
+		fill := g.theme.Meta.mix(g.theme.Background, 0.85)
+		if hasColor {
+			fill = color.mix(g.theme.Background, 0.8)
+		}
+		pad = 2.5
+		bx, bw := x, width
+		decorate = func(top, height float64) {
+			g.backend.SetFillColor(fill)
+			g.backend.RoundedRect(bx, top, bw, height, 2.5, "F")
+		}
+		x += 2 * pad
+		width -= 4 * pad
+	}
+
+	g.setTextColor(text)
+	g.drawMessage(entry, x, width, fontSize, pad, decorate)
+}
+
+// entryEmoji returns the emoji images for an entry: the document's images
+// plus the entry's own
+func (g *PDFGenerator) entryEmoji(entry ChatEntry) map[string]string {
+	if len(entry.Emoji) == 0 {
+		return g.emojiImages
+	}
+	images := make(map[string]string, len(g.emojiImages)+len(entry.Emoji))
+	for emoji, path := range g.emojiImages {
+		images[emoji] = path
+	}
+	for emoji, path := range entry.Emoji {
+		images[emoji] = path
+	}
+	return images
+}
+
+// addReactions draws the entry's reactions on one line below the message
+func (g *PDFGenerator) addReactions(entry ChatEntry, x, width float64) {
+	const fontSize = 9
+	if len(entry.Reactions) == 0 {
+		return
+	}
+
+	parts := make([]string, len(entry.Reactions))
+	for i, r := range entry.Reactions {
+		parts[i] = fmt.Sprintf("%s %d", r.Emoji, r.Count)
+	}
+	g.setFont("", fontSize)
+	g.setTextColor(g.theme.Meta)
+	runs := tokenizeMessage(strings.Join(parts, "   "), g.entryEmoji(entry))
+	g.drawLines(g.wrapRuns(runs, width, fontSize), x, fontSize, 0, nil)
+}
+
+// formatSize formats a byte count for display
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// addSystemEntry draws a notice from the chat service as centered small
+// print, without an avatar or name line
+func (g *PDFGenerator) addSystemEntry(entry ChatEntry) {
+	const fontSize = 9
+	const entryGap = 4.0
+
+	width := (g.pageWidth - 2*g.margin) * 0.8
+	text := entry.Message
+	if !entry.Timestamp.IsZero() {
+		text = entry.Timestamp.Format("2006-01-02 15:04") + " · " + text
+	}
+
+	g.setFont("I", fontSize)
+	g.setTextColor(g.theme.Meta)
+	for _, l := range g.wrapRuns(tokenizeMessage(text, g.entryEmoji(entry)), width, fontSize) {
+		g.drawLines([]line{l}, (g.pageWidth-l.width)/2, fontSize, 0, nil)
+	}
+	g.y += entryGap
+}
+
+// addEntry draws one chat entry: the avatar, a user name and timestamp line,
+// and the message below it, quoting the parent of a reply and indenting
+// nested replies
+func (g *PDFGenerator) addEntry(item threadItem) {
+	const metaHeight = 6.0
+	const entryGap = 6.0
+
+	entry := item.entry
+
+	if entry.Kind == KindSystem {
+		g.addSystemEntry(entry)
+		return
+	}
+	if entry.Kind == KindAction {
+		entry = actionEntry(entry)
+	}
+
+	// Keep the name line together with the quote and first line of the message
+	first := metaHeight + 12*ptToMM*lineSpacing
+	if item.parent != nil {
+		first += quoteHeight
+	}
+	if g.y+first > g.contentBottom() {
+		g.newPage()
+	}
+
+	top := g.y
+	indent := float64(item.depth) * threadIndent
+	x := g.margin + indent
+	width := g.pageWidth - (2 * g.margin) - indent
+	if !g.hideAvatars {
+		g.drawAvatar(entry, x, top)
+		x += avatarSize + avatarGap
+		width -= avatarSize + avatarGap
+	}
+
+	// Add user and timestamp
+	baseline := top + metaHeight*0.7
+	g.setFont("B", 10)
+	g.setTextColor(g.theme.Title)
+	g.backend.Text(x, baseline, entry.User)
+	nameWidth := g.stringWidth(entry.User)
+	if entry.User != "" {
+		nameWidth += 3
+	}
+
+	// Entries from sources without times, such as LLM transcripts, have none
+	if !entry.Timestamp.IsZero() {
+		timestamp := entry.Timestamp.Format("2006-01-02 15:04:05")
+		g.setFont("", 9)
+		g.setTextColor(g.theme.Meta)
+		g.backend.Text(x+nameWidth, baseline, timestamp)
+	}
+
+	// Add message, with mapped emoji drawn inline as images
+	page := g.backend.PageNo()
+	g.y = top + metaHeight
+	if item.parent != nil {
+		g.addQuote(item.parent, x, width)
+	}
+	if entry.Message != "" || len(entry.Attachments) == 0 {
+		g.addMessage(entry, x, width)
+	}
+	g.addAttachments(entry, x, width, false)
+	g.addReactions(entry, x, width)
+
+	// Leave room for the avatar when the message is shorter than it
+	if !g.hideAvatars && g.backend.PageNo() == page && g.y < top+avatarSize {
+		g.y = top + avatarSize
+	}
+	g.y += entryGap
+}
+
+// actionEntry returns an action as a message that reads "User does
+// something", all in italics
+func actionEntry(entry ChatEntry) ChatEntry {
+	prefix := entry.User + " "
+	spans := make([]Span, 0, len(entry.Spans)+1)
+	spans = append(spans, Span{End: len(prefix) + len(entry.Message), Style: StyleItalic})
+	for _, s := range entry.Spans {
+		s.Start += len(prefix)
+		s.End += len(prefix)
+		spans = append(spans, s)
+	}
+	entry.Message = prefix + entry.Message
+	entry.Spans = spans
+	return entry
+}
+
+// render lays out the entries; headers and footers come from the page hooks.
+// Backends without a total-pages alias get a first pass that only counts
+// pages.
+func (g *PDFGenerator) render() {
+	g.generatedAt = time.Now()
+
+	g.totalPages = g.backend.TotalPagesAlias()
+	if g.totalPages == "" {
+		target := g.backend
+		counter := &pageCounter{Backend: target}
+		g.backend = counter
+		g.layout()
+		g.backend = target
+		g.totalPages = strconv.Itoa(counter.pages)
+	}
+
+	g.layout()
+}
+
+// layout draws every entry, starting on a new page
+func (g *PDFGenerator) layout() {
+	g.newPage()
+
+	items := g.threadItems()
+	for i, item := range items {
+		if g.pageLayout == LayoutBubbles {
+			var prev *threadItem
+			if i > 0 {
+				prev = &items[i-1]
+			}
+			g.addBubble(item, prev)
+			continue
+		}
+		g.addEntry(item)
+	}
+}
@@ -0,0 +1,290 @@
+package chatpdf
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind classifies a piece of source code for highlighting
+type tokenKind int
+
+const (
+	tokenPlain tokenKind = iota
+	tokenKeyword
+	tokenBuiltin // predeclared types, constants and functions, and shell variables
+	tokenString
+	tokenNumber
+	tokenComment
+)
+
+// codeToken is a piece of source code of one kind
+type codeToken struct {
+	text string
+	kind tokenKind
+}
+
+// codeLanguage describes the lexical syntax of a language, as far as it is
+// needed for highlighting
+type codeLanguage struct {
+	keywords     map[string]bool
+	builtins     map[string]bool
+	foldCase     bool     // keywords match in any case, as in SQL
+	lineComments []string // markers that start a comment running to the end of the line
+	wordComments bool     // line comments only start at the beginning of a word
+	blockComment [2]string
+	quotes       string   // characters that start a string with backslash escapes
+	rawQuotes    string   // characters that start a string without escapes, which may span lines
+	longQuotes   []string // multi-character quotes, such as Python's """
+	multiline    bool     // strings in quotes may span lines
+	variables    bool     // $NAME and ${...} are variables, as in shells
+	identSymbols string   // characters other than letters, digits and _ allowed in names
+}
+
+// words builds a set of words from a space separated list
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+// codeLanguages lists the highlighted languages by name
+var codeLanguages = map[string]*codeLanguage{
+	"go": {
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var`),
+		builtins: words(`true false nil iota any bool byte comparable complex64 complex128 error float32
+			float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr
+			append cap clear close complex copy delete imag len make max min new panic print println
+			real recover`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		rawQuotes:    "`",
+	},
+	"python": {
+		keywords: words(`False None True and as assert async await break class continue def del elif else
+			except finally for from global if import in is lambda nonlocal not or pass raise return try
+			while with yield match case`),
+		builtins: words(`self cls print len range int str float list dict set tuple bool bytes object type
+			isinstance enumerate zip map filter open super sorted reversed sum min max any all abs repr
+			iter next getattr setattr hasattr Exception ValueError TypeError KeyError`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		longQuotes:   []string{`"""`, `'''`},
+	},
+	"javascript": {
+		keywords: words(`async await break case catch class const continue debugger default delete do else
+			enum export extends finally for from function get if implements import in instanceof
+			interface let new of private protected public readonly return set static super switch this
+			throw try type typeof var void while with yield as`),
+		builtins: words(`true false null undefined NaN Infinity console Math JSON Promise Object Array
+			String Number Boolean Error Map Set Symbol Date RegExp window document require module exports
+			string number boolean any unknown never`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		rawQuotes:    "`",
+		identSymbols: "$",
+	},
+	"sql": {
+		keywords: words(`select from where and or not insert into values update set delete create table drop
+			alter add column index primary key foreign references join inner left right full outer cross
+			natural on using as group by order having limit offset union all distinct case when then else
+			end is null like ilike in between exists with recursive returning default constraint unique
+			check view if begin commit rollback transaction asc desc grant revoke truncate explain`),
+		builtins: words(`int integer bigint smallint serial bigserial text varchar char boolean bool date
+			time timestamp timestamptz interval numeric decimal real float double json jsonb uuid count sum
+			avg min max coalesce nullif now cast true false`),
+		foldCase:     true,
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `'"`,
+	},
+	"shell": {
+		keywords: words(`if then else elif fi for while until do done case esac function in return export
+			local readonly select break continue exit shift source alias unset set declare time`),
+		builtins: words(`echo cd pwd ls printf read test true false eval exec trap wait kill grep sed awk
+			cat mkdir rm cp mv chmod chown sudo curl wget tar git go make docker kubectl npm pip python`),
+		lineComments: []string{"#"},
+		wordComments: true,
+		quotes:       `"`,
+		rawQuotes:    "'",
+		multiline:    true,
+		variables:    true,
+	},
+}
+
+// codeLanguageNames maps the names code blocks are labelled with to the
+// languages in codeLanguages
+var codeLanguageNames = map[string]string{
+	"go": "go", "golang": "go",
+	"python": "python", "py": "python", "python3": "python",
+	"javascript": "javascript", "js": "javascript", "jsx": "javascript", "mjs": "javascript",
+	"typescript": "javascript", "ts": "javascript", "tsx": "javascript", "json": "javascript",
+	"sql": "sql", "postgresql": "sql", "postgres": "sql", "mysql": "sql", "sqlite": "sql", "plsql": "sql",
+	"shell": "shell", "sh": "shell", "bash": "shell", "zsh": "shell", "console": "shell", "shell-session": "shell",
+}
+
+// lookupCodeLanguage returns the language a code block is labelled with,
+// or nil when it is not highlighted
+func lookupCodeLanguage(name string) *codeLanguage {
+	return codeLanguages[codeLanguageNames[strings.ToLower(strings.TrimSpace(name))]]
+}
+
+// lexCode splits source code into tokens. Strings and comments may span
+// lines; the newlines stay inside their tokens.
+func lexCode(src string, lang *codeLanguage) []codeToken {
+	var tokens []codeToken
+	emit := func(text string, kind tokenKind) {
+		if n := len(tokens); n > 0 && tokens[n-1].kind == kind {
+			tokens[n-1].text += text
+			return
+		}
+		tokens = append(tokens, codeToken{text, kind})
+	}
+	if lang == nil {
+		return []codeToken{{src, tokenPlain}}
+	}
+
+	isIdent := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(lang.identSymbols, r)
+	}
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		prev, _ := utf8.DecodeLastRuneInString(src[:i])
+		atWord := i == 0 || unicode.IsSpace(prev)
+
+		if end := lang.comment(rest, atWord); end > 0 {
+			emit(rest[:end], tokenComment)
+			i += end
+			continue
+		}
+		if end := lang.str(rest); end > 0 {
+			emit(rest[:end], tokenString)
+			i += end
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case lang.variables && r == '$' && len(rest) > 1:
+			end := 1 + shellVariable(rest[1:])
+			emit(rest[:end], tokenBuiltin)
+			i += end
+		case unicode.IsDigit(r) && !isIdent(prev):
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !(r == '.' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			emit(rest[:end], tokenNumber)
+			i += end
+		case isIdent(r):
+			end := strings.IndexFunc(rest, func(r rune) bool { return !isIdent(r) })
+			if end < 0 {
+				end = len(rest)
+			}
+			word := rest[:end]
+			key := word
+			if lang.foldCase {
+				key = strings.ToLower(word)
+			}
+			switch {
+			case lang.keywords[key]:
+				emit(word, tokenKeyword)
+			case lang.builtins[key]:
+				emit(word, tokenBuiltin)
+			default:
+				emit(word, tokenPlain)
+			}
+			i += end
+		default:
+			emit(rest[:size], tokenPlain)
+			i += size
+		}
+	}
+	return tokens
+}
+
+// comment returns the length of a comment at the start of s, or 0
+func (lang *codeLanguage) comment(s string, atWord bool) int {
+	for _, marker := range lang.lineComments {
+		if strings.HasPrefix(s, marker) && (atWord || !lang.wordComments) {
+			if end := strings.IndexByte(s, '\n'); end >= 0 {
+				return end
+			}
+			return len(s)
+		}
+	}
+	if open, close := lang.blockComment[0], lang.blockComment[1]; open != "" && strings.HasPrefix(s, open) {
+		if end := strings.Index(s[len(open):], close); end >= 0 {
+			return len(open) + end + len(close)
+		}
+		return len(s)
+	}
+	return 0
+}
+
+// str returns the length of a string literal at the start of s, or 0.
+// Ordinary strings end at the end of the line when they are not closed.
+func (lang *codeLanguage) str(s string) int {
+	for _, q := range lang.longQuotes {
+		if strings.HasPrefix(s, q) {
+			if end := strings.Index(s[len(q):], q); end >= 0 {
+				return len(q) + end + len(q)
+			}
+			return len(s)
+		}
+	}
+	if s == "" {
+		return 0
+	}
+	q := s[0]
+	switch {
+	case strings.IndexByte(lang.rawQuotes, q) >= 0:
+		if end := strings.IndexByte(s[1:], q); end >= 0 {
+			return end + 2
+		}
+		return len(s)
+	case strings.IndexByte(lang.quotes, q) >= 0:
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case q:
+				return i + 1
+			case '\n':
+				if !lang.multiline {
+					return i
+				}
+			}
+		}
+		return len(s)
+	}
+	return 0
+}
+
+// shellVariable returns the length of the variable name after a $
+func shellVariable(s string) int {
+	if s[0] == '{' {
+		if end := strings.IndexByte(s, '}'); end >= 0 {
+			return end + 1
+		}
+		return 0
+	}
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	switch {
+	case end < 0:
+		return len(s)
+	case end == 0 && strings.ContainsRune("?@#*!$-", rune(s[0])):
+		// Special parameters such as $? and $@
+		return 1
+	}
+	return end
+}
@@ -0,0 +1,99 @@
+package chatpdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLexCode(t *testing.T) {
+	kinds := map[tokenKind]string{
+		tokenKeyword: "keyword",
+		tokenBuiltin: "builtin",
+		tokenString:  "string",
+		tokenNumber:  "number",
+		tokenComment: "comment",
+	}
+	tests := []struct {
+		name string
+		lang string
+		src  string
+		want []string // the tokens that are not plain text, as kind and text
+	}{
+		{
+			name: "go",
+			lang: "golang",
+			src:  "func f() int { return len(\"a\\\"b\") + 0x1F } // done\n/* a\nb */ var s = `raw\nline`",
+			want: []string{`keyword "func"`, `builtin "int"`, `keyword "return"`, `builtin "len"`, `string "\"a\\\"b\""`,
+				`number "0x1F"`, `comment "// done"`, `comment "/* a\nb */"`, `keyword "var"`, "string \"`raw\\nline`\""},
+		},
+		{
+			name: "go names with digits",
+			lang: "go",
+			src:  "x2 := int64(3.5e2)",
+			want: []string{`builtin "int64"`, `number "3.5e2"`},
+		},
+		{
+			name: "go unclosed string ends at the line",
+			lang: "go",
+			src:  "s := \"open\nnil",
+			want: []string{`string "\"open"`, `builtin "nil"`},
+		},
+		{
+			name: "python",
+			lang: "py",
+			src:  "def f(self):\n    \"\"\"Doc\n    string\"\"\"\n    return None # why",
+			want: []string{`keyword "def"`, `builtin "self"`, `string "\"\"\"Doc\n    string\"\"\""`, `keyword "return"`, `keyword "None"`, `comment "# why"`},
+		},
+		{
+			name: "javascript",
+			lang: "ts",
+			src:  "const $el = `a ${b}`; // c",
+			want: []string{`keyword "const"`, "string \"`a ${b}`\"", `comment "// c"`},
+		},
+		{
+			name: "sql in any case",
+			lang: "postgres",
+			src:  "SELECT count(*) FROM t -- all\nWhere name = 'it''s'",
+			want: []string{`keyword "SELECT"`, `builtin "count"`, `keyword "FROM"`, `comment "-- all"`, `keyword "Where"`,
+				`string "'it''s'"`},
+		},
+		{
+			name: "shell",
+			lang: "bash",
+			src:  "echo \"$HOME ${X}\" a#b $? # note\nexit 1",
+			want: []string{`builtin "echo"`, `string "\"$HOME ${X}\""`, `builtin "$?"`, `comment "# note"`, `keyword "exit"`, `number "1"`},
+		},
+		{
+			name: "shell strings span lines",
+			lang: "sh",
+			src:  "echo 'a\nb' \"c\nd\"",
+			want: []string{`builtin "echo"`, `string "'a\nb'"`, `string "\"c\nd\""`},
+		},
+		{
+			name: "unknown language",
+			lang: "cobol",
+			src:  "MOVE 1 TO X",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := lexCode(tt.src, lookupCodeLanguage(tt.lang))
+			var src strings.Builder
+			var got []string
+			for _, tok := range tokens {
+				src.WriteString(tok.text)
+				if tok.kind != tokenPlain {
+					got = append(got, fmt.Sprintf("%s %q", kinds[tok.kind], tok.text))
+				}
+			}
+			if src.String() != tt.src {
+				t.Errorf("tokens join to %q, want the source %q", src.String(), tt.src)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("tokens:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package chatpdf
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// htmlNode is an element or text node of a parsed HTML fragment. The
+// parser is lenient: it is meant for message bodies and simple exported
+// pages, not for arbitrary web pages.
+type htmlNode struct {
+	tag      string // lower case; "" for text nodes and the root
+	attrs    map[string]string
+	text     string // decoded text of a text node
+	children []*htmlNode
+	parent   *htmlNode
+}
+
+// htmlVoid lists the elements that never have content or an end tag
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRaw lists the elements whose content is not markup
+var htmlRaw = map[string]bool{"script": true, "style": true, "title": true, "textarea": true}
+
+// htmlAttr matches one attribute of a start tag
+var htmlAttr = regexp.MustCompile(`([^\s"'<>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+
+// parseHTML parses s into a tree. End tags close the nearest open element
+// with the same name; stray end tags are ignored.
+func parseHTML(s string) *htmlNode {
+	root := &htmlNode{}
+	cur := root
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			cur.addText(s)
+			break
+		}
+		if lt > 0 {
+			cur.addText(s[:lt])
+			s = s[lt:]
+		}
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				return root
+			}
+			s = s[end+3:]
+			continue
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return root
+			}
+			s = s[end+1:]
+			continue
+		}
+
+		end := tagEnd(s)
+		if end < 0 || len(s) < 2 || !(s[1] == '/' || isLetter(s[1])) {
+			// A lone "<" is text
+			cur.addText("<")
+			s = s[1:]
+			continue
+		}
+		tag := s[1:end]
+		s = s[end+1:]
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToLower(strings.TrimSpace(tag[1:]))
+			for n := cur; n != root; n = n.parent {
+				if n.tag == name {
+					cur = n.parent
+					break
+				}
+			}
+			continue
+		}
+
+		selfClosing := strings.HasSuffix(tag, "/")
+		tag = strings.TrimSuffix(tag, "/")
+		name := tag
+		if i := strings.IndexAny(tag, " \t\r\n"); i >= 0 {
+			name = tag[:i]
+		}
+		node := &htmlNode{tag: strings.ToLower(name), attrs: parseAttrs(tag[len(name):]), parent: cur}
+		cur.children = append(cur.children, node)
+
+		if htmlRaw[node.tag] && !selfClosing {
+			closing := "</" + node.tag
+			i := strings.Index(strings.ToLower(s), closing)
+			if i < 0 {
+				i = len(s)
+			}
+			node.addText(s[:i])
+			s = s[i:]
+			if j := strings.IndexByte(s, '>'); j >= 0 {
+				s = s[j+1:]
+			}
+			continue
+		}
+		if !selfClosing && !htmlVoid[node.tag] {
+			cur = node
+		}
+	}
+	return root
+}
+
+// tagEnd returns the index of the ">" closing the tag at the start of s,
+// skipping quoted attribute values, or -1
+func tagEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseAttrs parses the attributes of a start tag
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range htmlAttr.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = htmlUnescape(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// addText appends decoded text to the node
+func (n *htmlNode) addText(s string) {
+	n.children = append(n.children, &htmlNode{text: htmlUnescape(s), parent: n})
+}
+
+// hasClass reports whether the element's class attribute contains any of
+// the given class names
+func (n *htmlNode) hasClass(names ...string) bool {
+	for _, c := range strings.Fields(n.attrs["class"]) {
+		for _, name := range names {
+			if strings.EqualFold(c, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// find returns the first descendant, in document order, that matches
+func (n *htmlNode) find(match func(*htmlNode) bool) *htmlNode {
+	for _, c := range n.children {
+		if c.tag != "" && match(c) {
+			return c
+		}
+		if found := c.find(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns the outermost descendants that match
+func (n *htmlNode) findAll(match func(*htmlNode) bool) []*htmlNode {
+	var found []*htmlNode
+	for _, c := range n.children {
+		if c.tag != "" && match(c) {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, c.findAll(match)...)
+	}
+	return found
+}
+
+// remove detaches the node from its parent
+func (n *htmlNode) remove() {
+	if n.parent == nil {
+		return
+	}
+	siblings := n.parent.children
+	for i, c := range siblings {
+		if c == n {
+			n.parent.children = append(siblings[:i], siblings[i+1:]...)
+			break
+		}
+	}
+	n.parent = nil
+}
+
+// htmlEntities lists the named character references that commonly appear
+// in chat and mail bodies
+var htmlEntities = map[string]string{
+	"amp": "&", "lt": "<", "gt": ">", "quot": "\"", "apos": "'", "nbsp": " ",
+	"ndash": "–", "mdash": "—", "hellip": "…", "lsquo": "‘", "rsquo": "’", "ldquo": "“", "rdquo": "”",
+	"bull": "•", "middot": "·", "copy": "©", "reg": "®", "trade": "™", "euro": "€", "pound": "£",
+	"yen": "¥", "cent": "¢", "deg": "°", "times": "×", "divide": "÷", "laquo": "«", "raquo": "»",
+	"shy": "­", "zwj": "‍", "zwnj": "‌",
+}
+
+// htmlUnescape decodes character references in s
+func htmlUnescape(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '&')
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		end := strings.IndexByte(s, ';')
+		if end < 2 || end > 12 {
+			b.WriteByte('&')
+			s = s[1:]
+			continue
+		}
+		ref := s[1:end]
+		if decoded, ok := decodeReference(ref); ok {
+			b.WriteString(decoded)
+			s = s[end+1:]
+			continue
+		}
+		b.WriteByte('&')
+		s = s[1:]
+	}
+}
+
+// decodeReference decodes the name or number of a character reference
+func decodeReference(ref string) (string, bool) {
+	if ref[0] != '#' {
+		decoded, ok := htmlEntities[ref]
+		return decoded, ok
+	}
+	var n uint64
+	var err error
+	if len(ref) > 1 && (ref[1] == 'x' || ref[1] == 'X') {
+		n, err = strconv.ParseUint(ref[2:], 16, 32)
+	} else {
+		n, err = strconv.ParseUint(ref[1:], 10, 32)
+	}
+	if err != nil || !utf8.ValidRune(rune(n)) || n == 0 {
+		return "", false
+	}
+	return string(rune(n)), true
+}
+
+// htmlBlocks lists the elements that start on a new line
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "ul": true, "ol": true, "li": true, "table": true, "tr": true,
+	"blockquote": true, "pre": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "hr": true, "section": true, "article": true, "header": true, "footer": true,
+}
+
+// htmlStyles maps inline elements to span styles
+var htmlStyles = map[string]SpanStyle{
+	"b": StyleBold, "strong": StyleBold, "i": StyleItalic, "em": StyleItalic, "cite": StyleItalic,
+	"u": StyleUnderline, "ins": StyleUnderline, "s": StyleStrike, "strike": StyleStrike, "del": StyleStrike,
+	"code": StyleCode, "kbd": StyleCode, "samp": StyleCode, "tt": StyleCode, "pre": StyleCodeBlock,
+	"a": StyleLink, "at": StyleLink, "h1": StyleBold, "h2": StyleBold, "h3": StyleBold, "h4": StyleBold,
+	"h5": StyleBold, "h6": StyleBold, "blockquote": StyleItalic,
+}
+
+// htmlText renders HTML as plain text with formatting spans
+type htmlText struct {
+	b     strings.Builder
+	spans []Span
+	open  []int // start offsets of the elements being walked
+	pre   int   // depth of <pre> elements, which keep their whitespace
+}
+
+// htmlToText converts an HTML fragment to text and formatting spans. Block
+// elements start new lines, list items get bullets, whitespace collapses
+// as in a browser, and images are replaced by their alt text.
+func htmlToText(s string) (string, []Span) {
+	return nodeText(parseHTML(s))
+}
+
+// nodeText converts a parsed HTML node to text and formatting spans
+func nodeText(n *htmlNode) (string, []Span) {
+	var t htmlText
+	t.walk(n)
+
+	text := strings.TrimRight(t.b.String(), " \n")
+	lead := len(text) - len(strings.TrimLeft(text, " \n"))
+	text = text[lead:]
+
+	var spans []Span
+	for _, s := range t.spans {
+		s.Start, s.End = clampOffset(s.Start-lead, text), clampOffset(s.End-lead, text)
+		if s.End > s.Start {
+			spans = append(spans, s)
+		}
+	}
+	return text, spans
+}
+
+func (t *htmlText) walk(n *htmlNode) {
+	if n.tag == "" && n.parent != nil {
+		t.write(n.text)
+		return
+	}
+
+	switch n.tag {
+	case "script", "style", "head", "title":
+		return
+	case "br":
+		t.b.WriteString("\n")
+		return
+	case "img", "emoji":
+		alt := n.attrs["alt"]
+		if alt == "" && n.tag == "img" {
+			alt = "[image]"
+		}
+		t.write(alt)
+		return
+	case "td", "th":
+		t.space()
+	}
+
+	if htmlBlocks[n.tag] {
+		t.newline()
+	}
+	if n.tag == "li" {
+		t.b.WriteString("• ")
+	}
+	if n.tag == "pre" {
+		t.pre++
+	}
+
+	t.open = append(t.open, t.b.Len())
+	for _, c := range n.children {
+		t.walk(c)
+	}
+	start := t.open[len(t.open)-1]
+	t.open = t.open[:len(t.open)-1]
+
+	if n.tag == "pre" {
+		t.pre--
+	}
+	if style, ok := htmlStyles[n.tag]; ok && t.b.Len() > start {
+		span := Span{Start: start, End: t.b.Len(), Style: style}
+		switch n.tag {
+		case "a":
+			span.URL = n.attrs["href"]
+		case "pre":
+			span.Language = codeClass(n)
+		}
+		t.spans = append(t.spans, span)
+	}
+	if htmlBlocks[n.tag] {
+		t.newline()
+	}
+}
+
+// write adds text, collapsing whitespace unless inside <pre>
+func (t *htmlText) write(s string) {
+	if t.pre > 0 {
+		t.b.WriteString(s)
+		return
+	}
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			t.space()
+			continue
+		}
+		t.b.WriteRune(r)
+	}
+}
+
+// space adds a single space unless the text already ends in whitespace
+func (t *htmlText) space() {
+	if out := t.b.String(); out != "" && !strings.HasSuffix(out, " ") && !strings.HasSuffix(out, "\n") {
+		t.b.WriteByte(' ')
+	}
+}
+
+// newline ends the current line unless it is empty
+func (t *htmlText) newline() {
+	out := t.b.String()
+	if out == "" || strings.HasSuffix(out, "\n") {
+		return
+	}
+	if strings.HasSuffix(out, " ") {
+		// Drop the space before the line break; spans must not reach past
+		// it, and elements that opened in the space start on the next line
+		trimmed := strings.TrimRight(out, " ")
+		t.b.Reset()
+		t.b.WriteString(trimmed)
+		for i := range t.spans {
+			t.spans[i].Start = min(t.spans[i].Start, len(trimmed))
+			t.spans[i].End = min(t.spans[i].End, len(trimmed))
+		}
+		for i, start := range t.open {
+			if start > len(trimmed) {
+				t.open[i] = len(trimmed) + 1
+			}
+		}
+	}
+	t.b.WriteByte('\n')
+}
+
+// codeClass returns the language of a <pre> block from a class such as
+// "language-go" on it or on the <code> element inside it
+func codeClass(pre *htmlNode) string {
+	nodes := append([]*htmlNode{pre}, pre.findAll(func(n *htmlNode) bool { return n.tag == "code" })...)
+	for _, n := range nodes {
+		for _, class := range strings.Fields(n.attrs["class"]) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if lang, ok := strings.CutPrefix(class, prefix); ok {
+					return lang
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package chatpdf
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		text  string
+		spans string
+	}{
+		{
+			name:  "inline styles and links",
+			in:    `<b>bold</b> <i>it</i> <a href="https://example.com">site</a> &amp; <code>x&lt;y</code>`,
+			text:  "bold it site & x<y",
+			spans: `"bold" 1, "it" 2, "site" 32 https://example.com, "x<y" 16`,
+		},
+		{
+			name:  "whitespace collapses",
+			in:    "  one \n\t two  <span> three </span>",
+			text:  "one two three",
+			spans: "",
+		},
+		{
+			name:  "blocks and lists",
+			in:    "<h2>Title</h2><p>para</p><ul><li>a</li><li>b</li></ul>line<br>break",
+			text:  "Title\npara\n• a\n• b\nline\nbreak",
+			spans: `"Title" 1`,
+		},
+		{
+			name:  "span ending in a space before a block",
+			in:    "<b>bold </b><p>next</p>",
+			text:  "bold\nnext",
+			spans: `"bold" 1`,
+		},
+		{
+			name:  "element opened in a trimmed space",
+			in:    "<pre>a  <b><div>b</div></b></pre>",
+			text:  "a\nb",
+			spans: `"b" 1, "a\nb" 64`,
+		},
+		{
+			name:  "pre keeps whitespace",
+			in:    `<pre class="language-go">if x {` + "\n" + `  y()` + "\n" + `}</pre>`,
+			text:  "if x {\n  y()\n}",
+			spans: `"if x {\n  y()\n}" 64 go`,
+		},
+		{
+			name:  "images and scripts",
+			in:    `<img src="a.png" alt="logo"><script>alert(1)</script><img src="b.png">`,
+			text:  "logo[image]",
+			spans: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, spans := htmlToText(tt.in)
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+				return
+			}
+			if got := spanList(text, spans); got != tt.spans {
+				t.Errorf("spans = %s, want %s", got, tt.spans)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package chatpdf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImportOptions selects what an importer reads from an export
+type ImportOptions struct {
+	// Channel names the channel or conversation to read from exports that
+	// hold several; it may be left empty when the export has only one
+	Channel string
+
+	// From and To limit the entries to a time range; either may be zero
+	// for an open end. Both ends are inclusive. Entries without a time,
+	// as in most LLM transcripts, are kept.
+	From time.Time
+	To   time.Time
+
+	// MediaDir is the directory holding the media files of exports that
+	// reference them by name; it defaults to the directory of the export
+	MediaDir string
+
+	// Preset names the format of line based logs, one of LinePresets; it
+	// is detected from the log when empty
+	Preset string
+
+	// LinePattern is a regular expression for the message lines of line
+	// based logs, with named groups "user" and "msg" and optionally "ts"
+	// for the time. It replaces the preset's message pattern.
+	LinePattern string
+
+	// TimeLayout is the time.Parse layout of the "ts" group of line based
+	// logs and of the timestamp column of CSV files; it defaults to the
+	// preset's or common layouts
+	TimeLayout string
+
+	// Location is the time zone of imported times that do not name one,
+	// such as those of WhatsApp exports, line based logs and CSV files;
+	// nil means local time
+	Location *time.Location
+
+	// Columns maps the fields "timestamp", "user", "message", "color" and
+	// "icon" to CSV columns by header name or 1-based index
+	Columns map[string]string
+
+	// Warn, when set, is called for problems that do not stop the import,
+	// such as text in a charset that cannot be decoded
+	Warn func(Warning)
+}
+
+// warn reports a problem through opts.Warn, if set
+func (opts ImportOptions) warn(w Warning) {
+	if opts.Warn != nil {
+		opts.Warn(w)
+	}
+}
+
+// mediaDir returns the directory the media files of the export at path are
+// looked up in: opts.MediaDir, or else the export itself when it is a
+// directory and the directory holding it otherwise
+func (opts ImportOptions) mediaDir(path string) string {
+	if opts.MediaDir != "" {
+		return opts.MediaDir
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path
+	}
+	return filepath.Dir(path)
+}
+
+// location returns the time zone for times without one
+func (opts ImportOptions) location() *time.Location {
+	if opts.Location == nil {
+		return time.Local
+	}
+	return opts.Location
+}
+
+// inRange reports whether t falls within the options' time range. The zero
+// time stands for an unknown time and is always in range.
+func (opts ImportOptions) inRange(t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+	if !opts.From.IsZero() && t.Before(opts.From) {
+		return false
+	}
+	if !opts.To.IsZero() && t.After(opts.To) {
+		return false
+	}
+	return true
+}
+
+// Filter drops the entries outside the options' time range, reusing the
+// storage of entries
+func (opts ImportOptions) Filter(entries []ChatEntry) []ChatEntry {
+	kept := entries[:0]
+	for _, entry := range entries {
+		if opts.inRange(entry.Timestamp) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// importer reads the chat entries of an export at path
+type importer func(path string, opts ImportOptions) ([]ChatEntry, error)
+
+// importers lists the supported input formats by name
+var importers = map[string]importer{
+	"json":     importJSON,
+	"slack":    ImportSlack,
+	"discord":  ImportDiscord,
+	"whatsapp": ImportWhatsApp,
+	"telegram": ImportTelegram,
+	"llm":      ImportLLM,
+	"teams":    ImportTeams,
+	"irc":      ImportIRC,
+	"csv":      ImportCSV,
+	"tsv":      ImportCSV,
+	"matrix":   ImportMatrix,
+	"email":    ImportEmail,
+}
+
+// ImportFormats returns the names of the supported input formats in sorted order
+func ImportFormats() []string {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Import reads chat entries from path in the named format. An empty format
+// is detected from the path, defaulting to "json".
+func Import(format, path string, opts ImportOptions) ([]ChatEntry, error) {
+	if format == "" {
+		format = DetectFormat(path)
+	}
+	load, ok := importers[strings.ToLower(format)]
+	if !ok {
+		return nil, &OptionError{Option: "Format", Value: format, Valid: ImportFormats()}
+	}
+	return load(path, opts)
+}
+
+// DetectFormat guesses the input format of path from its layout and the
+// start of its content
+func DetectFormat(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "channels.json")); err == nil {
+		return "slack"
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv":
+		return "csv"
+	case ".eml", ".mbox":
+		return "email"
+	}
+	if emls, _ := filepath.Glob(filepath.Join(path, "*.eml")); len(emls) > 0 {
+		return "email"
+	}
+
+	head := readHead(path, 4096)
+	trimmed := strings.TrimLeft(strings.TrimPrefix(head, "\ufeff"), " \t\r\n")
+	if strings.HasPrefix(head, "From ") || emailHeaders.MatchString(head) {
+		return "email"
+	}
+	if strings.HasPrefix(trimmed, "{") && strings.Contains(head, `"guild"`) {
+		return "discord"
+	}
+	if strings.HasPrefix(trimmed, "{") && (strings.Contains(head, `"room_name"`) || strings.Contains(head, `"origin_server_ts"`)) {
+		return "matrix"
+	}
+	if strings.HasPrefix(trimmed, "<") || strings.Contains(head, `"createdDateTime"`) || strings.Contains(head, `"@odata.context"`) {
+		return "teams"
+	}
+	if strings.HasPrefix(trimmed, "{") && (strings.Contains(head, `"date_unixtime"`) || strings.Contains(head, `"text_entities"`) ||
+		strings.Contains(head, `"personal_information"`)) {
+		return "telegram"
+	}
+	if strings.Contains(head, `"role"`) {
+		return "llm"
+	}
+	if first, _, _ := strings.Cut(trimmed, "\n"); whatsappLine.MatchString(strings.TrimSuffix(first, "\r")) {
+		return "whatsapp"
+	}
+	if first, _, _ := strings.Cut(trimmed, "\n"); detectPreset([]string{strings.TrimSuffix(first, "\r")}) != "" {
+		return "irc"
+	}
+	return "json"
+}
+
+// readHead returns up to n bytes from the start of the file at path, or ""
+// when it cannot be read
+func readHead(path string, n int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	n, _ = io.ReadFull(f, buf)
+	return string(buf[:n])
+}
+
+// localFile resolves a media reference from an export to a local file.
+// Relative references are resolved against base; URLs and missing files
+// give "".
+func localFile(base, ref string) string {
+	if ref == "" || strings.Contains(ref, "://") {
+		return ""
+	}
+	path := ref
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, filepath.FromSlash(ref))
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// imageExts lists the image file types the backends can draw
+var imageExts = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true}
+
+// localImage is localFile limited to images the backends can draw
+func localImage(base, ref string) string {
+	path := localFile(base, ref)
+	if !imageExts[strings.ToLower(filepath.Ext(path))] {
+		return ""
+	}
+	return path
+}
+
+// importJSON reads a JSON or JSONL chat log
+func importJSON(path string, opts ImportOptions) ([]ChatEntry, error) {
+	entries, err := loadEntriesFile(path, opts.location())
+	if err != nil {
+		return nil, err
+	}
+	return opts.Filter(entries), nil
+}
+
+// sortEntries orders entries by timestamp, keeping the input order of
+// entries with the same time
+func sortEntries(entries []ChatEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+}
+
+// deletedText replaces the text of a deleted message
+const deletedText = "This message was deleted"
+
+// markDeleted replaces the text of an entry with a note, in italics, that
+// the message was deleted, giving the reason when there is one
+func markDeleted(entry *ChatEntry, reason string) {
+	entry.Message = deletedText
+	if reason != "" {
+		entry.Message += " (" + reason + ")"
+	}
+	entry.Spans = []Span{{End: len(entry.Message), Style: StyleItalic}}
+	entry.Attachments, entry.Reactions = nil, nil
+}
+
+// pathError prefixes err with the file it came from
+func pathError(path string, err error) error {
+	return fmt.Errorf("%s: %w", path, err)
+}
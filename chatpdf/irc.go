@@ -0,0 +1,318 @@
+package chatpdf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// linePreset describes the log format of an IRC client. Message and action
+// patterns have "ts", "user" and "msg" groups, event patterns "ts" and
+// "msg", and day patterns a "date" group for lines that start a new day.
+type linePreset struct {
+	message     *regexp.Regexp
+	action      *regexp.Regexp
+	event       *regexp.Regexp
+	day         *regexp.Regexp
+	timeLayouts []string
+	dayLayouts  []string
+}
+
+// linePresets lists the built-in IRC log formats by client name
+var linePresets = map[string]*linePreset{
+	// 10:00 <@alice> hello, with "--- Day changed" lines
+	"irssi": {
+		message:     regexp.MustCompile(`^(?P<ts>\d{1,2}:\d{2}(?::\d{2})?) <[ @+%~&]?(?P<user>[^>]+)> (?P<msg>.*)$`),
+		action:      regexp.MustCompile(`^(?P<ts>\d{1,2}:\d{2}(?::\d{2})?) +\* (?P<user>\S+) (?P<msg>.*)$`),
+		event:       regexp.MustCompile(`^(?P<ts>\d{1,2}:\d{2}(?::\d{2})?) -!- (?P<msg>.*)$`),
+		day:         regexp.MustCompile(`^--- (?:Log opened|Day changed) (?P<date>.+)$`),
+		timeLayouts: []string{"15:04:05", "15:04"},
+		dayLayouts:  []string{"Mon Jan 02 15:04:05 2006", "Mon Jan 02 2006", "Mon Jan _2 15:04:05 2006", "Mon Jan _2 2006"},
+	},
+	// 2024-03-01 10:00:00<TAB>@alice<TAB>hello
+	"weechat": {
+		message:     regexp.MustCompile(`^(?P<ts>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\t[@+%~&]?(?P<user>[^\t]+)\t(?P<msg>.*)$`),
+		action:      regexp.MustCompile(`^(?P<ts>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\t ?\*\t(?P<user>\S+) (?P<msg>.*)$`),
+		event:       regexp.MustCompile(`^(?P<ts>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\t(?:-->|<--|--|=!=)\t(?P<msg>.*)$`),
+		timeLayouts: []string{"2006-01-02 15:04:05"},
+	},
+	// [10:00:00] <alice> hello, one file per day named after the date
+	"znc": {
+		message:     regexp.MustCompile(`^\[(?P<ts>\d{2}:\d{2}:\d{2})\] <(?P<user>[^>]+)> (?P<msg>.*)$`),
+		action:      regexp.MustCompile(`^\[(?P<ts>\d{2}:\d{2}:\d{2})\] \* (?P<user>\S+) (?P<msg>.*)$`),
+		event:       regexp.MustCompile(`^\[(?P<ts>\d{2}:\d{2}:\d{2})\] \*\*\* (?P<msg>.*)$`),
+		timeLayouts: []string{"15:04:05"},
+	},
+}
+
+// lineTimeLayouts are tried for the "ts" group of a custom line pattern
+// when no time layout is given
+var lineTimeLayouts = append(append([]string{}, timestampLayouts...), "15:04:05", "15:04")
+
+// ircHostmask matches the user@host part of join, part and quit notices
+var ircHostmask = regexp.MustCompile(` [\[(]~?[^\s\])]*@[^\s\])]*[\])]`)
+
+// zncEvent matches ZNC's join, part and quit notices
+var zncEvent = regexp.MustCompile(`^(Joins|Parts|Quits): (\S+)(?: \((.*)\))?$`)
+
+// zncVerbs gives the verb for each ZNC notice
+var zncVerbs = map[string]string{"Joins": "joined", "Parts": "left", "Quits": "quit"}
+
+// fileDate matches a date in a log file name, as in "#go_20240301.log"
+var fileDate = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})`)
+
+// LinePresets returns the names of the built-in IRC log formats in sorted order
+func LinePresets() []string {
+	names := make([]string, 0, len(linePresets))
+	for name := range linePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ImportIRC reads a line based chat log, such as an IRC client's log.
+// opts.Preset picks a built-in format ("irssi", "weechat" or "znc"), which
+// is detected from the file when empty; opts.LinePattern gives a custom
+// pattern for message lines instead, and opts.TimeLayout the layout of its
+// times. Actions ("/me") become KindAction entries and notices such as
+// joins and parts KindSystem entries; other lines are skipped. Logs that
+// only write the time of day take the date from day change lines, from a
+// date in the file name, or else from the file's modification time. Times
+// are taken in opts.Location.
+func ImportIRC(path string, opts ImportOptions) ([]ChatEntry, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	preset, err := opts.linePreset(lines)
+	if err != nil {
+		return nil, pathError(path, err)
+	}
+
+	loc := opts.location()
+	day := logDay(path, loc)
+	var prev time.Time
+	var matched bool
+	var entries []ChatEntry
+	for i, text := range lines {
+		if preset.day != nil {
+			if m := preset.day.FindStringSubmatch(text); m != nil {
+				if d, ok := parseDay(m[preset.day.SubexpIndex("date")], preset.dayLayouts, loc); ok {
+					day, prev = d, time.Time{}
+				}
+				continue
+			}
+		}
+
+		entry, ts, ok := preset.parse(text)
+		if !ok {
+			continue
+		}
+		matched = true
+		if ts != "" {
+			t, dated, err := parseLineTime(ts, preset.timeLayouts, loc)
+			if err != nil {
+				return nil, pathError(path, &LineError{Line: i + 1, Err: err})
+			}
+			if !dated {
+				t = time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+				// A time before the previous line's means midnight passed
+				// without a day change line
+				if t.Before(prev) {
+					t = t.AddDate(0, 0, 1)
+					day = day.AddDate(0, 0, 1)
+				}
+			}
+			entry.Timestamp, prev = t, t
+		}
+		if opts.inRange(entry.Timestamp) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if !matched {
+		return nil, pathError(path, errors.New("no log lines match the line format"))
+	}
+	return entries, nil
+}
+
+// readLines reads the lines of a text file without line endings
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		lines = append(lines, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, pathError(path, err)
+	}
+	return lines, nil
+}
+
+// linePreset returns the line format selected by the options, detecting the
+// preset from lines when none is named
+func (opts ImportOptions) linePreset(lines []string) (*linePreset, error) {
+	var preset linePreset
+	switch {
+	case opts.Preset != "":
+		p, ok := linePresets[strings.ToLower(opts.Preset)]
+		if !ok {
+			return nil, &OptionError{Option: "Preset", Value: opts.Preset, Valid: LinePresets()}
+		}
+		preset = *p
+	case opts.LinePattern != "":
+		preset.timeLayouts = lineTimeLayouts
+	default:
+		name := detectPreset(lines)
+		if name == "" {
+			return nil, errors.New("no " + strings.Join(LinePresets(), ", ") + " log lines found; give a preset or a line pattern")
+		}
+		preset = *linePresets[name]
+	}
+
+	if opts.LinePattern != "" {
+		re, err := regexp.Compile(opts.LinePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line pattern: %w", err)
+		}
+		if re.SubexpIndex("user") < 0 || re.SubexpIndex("msg") < 0 {
+			return nil, errors.New(`line pattern needs "user" and "msg" groups`)
+		}
+		preset.message = re
+	}
+	if opts.TimeLayout != "" {
+		preset.timeLayouts = []string{opts.TimeLayout}
+	}
+	return &preset, nil
+}
+
+// detectPreset returns the preset matching most of the first lines, or ""
+// when none matches any
+func detectPreset(lines []string) string {
+	if len(lines) > 200 {
+		lines = lines[:200]
+	}
+	best, bestCount := "", 0
+	for _, name := range LinePresets() {
+		p := linePresets[name]
+		var count int
+		for _, text := range lines {
+			if p.matches(text) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = name, count
+		}
+	}
+	return best
+}
+
+// matches reports whether text is a line of the preset's format
+func (p *linePreset) matches(text string) bool {
+	for _, re := range []*regexp.Regexp{p.message, p.action, p.event, p.day} {
+		if re != nil && re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse converts a log line to an entry without its time, which is
+// returned unparsed; ok is false for lines of no known kind
+func (p *linePreset) parse(text string) (entry ChatEntry, ts string, ok bool) {
+	group := func(re *regexp.Regexp, m []string, name string) string {
+		if i := re.SubexpIndex(name); i >= 0 {
+			return m[i]
+		}
+		return ""
+	}
+
+	// Actions and notices first, since message patterns may match them too
+	if p.action != nil {
+		if m := p.action.FindStringSubmatch(text); m != nil {
+			entry = ChatEntry{Kind: KindAction, User: group(p.action, m, "user"), Message: group(p.action, m, "msg")}
+			return entry, group(p.action, m, "ts"), true
+		}
+	}
+	if p.event != nil {
+		if m := p.event.FindStringSubmatch(text); m != nil {
+			entry = ChatEntry{Kind: KindSystem, Message: ircEventText(group(p.event, m, "msg"))}
+			return entry, group(p.event, m, "ts"), true
+		}
+	}
+	if m := p.message.FindStringSubmatch(text); m != nil {
+		entry = ChatEntry{User: strings.TrimSpace(group(p.message, m, "user")), Message: group(p.message, m, "msg")}
+		return entry, group(p.message, m, "ts"), true
+	}
+	return ChatEntry{}, "", false
+}
+
+// ircEventText tidies a join, part or quit notice
+func ircEventText(msg string) string {
+	msg = ircHostmask.ReplaceAllString(msg, "")
+	if m := zncEvent.FindStringSubmatch(msg); m != nil {
+		msg = m[2] + " " + zncVerbs[m[1]]
+		if m[3] != "" {
+			msg += " (" + m[3] + ")"
+		}
+	}
+	return msg
+}
+
+// parseLineTime parses a time with the first matching layout; dated is
+// false when the layout has no date
+func parseLineTime(s string, layouts []string, loc *time.Location) (t time.Time, dated bool, err error) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, t.Year() != 0, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q", s)
+}
+
+// parseDay parses the date of a day change line
+func parseDay(s string, layouts []string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// logDay returns the day a log starts on: a date in its file name, or the
+// day it was last modified
+func logDay(path string, loc *time.Location) time.Time {
+	if m := fileDate.FindStringSubmatch(filepath.Base(path)); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+		}
+	}
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime().In(loc)
+	}
+	return time.Now().In(loc)
+}
@@ -0,0 +1,149 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectPreset(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{
+			name: "irssi",
+			lines: []string{
+				"--- Log opened Fri Mar 01 10:00:00 2024",
+				"10:00 -!- alice [~alice@host] has joined #go",
+				"10:01 <@alice> hello",
+				"10:02  * alice waves",
+			},
+			want: "irssi",
+		},
+		{
+			name: "weechat",
+			lines: []string{
+				"2024-03-01 10:00:00\t-->\talice (~alice@host) has joined #go",
+				"2024-03-01 10:01:00\t@alice\thello",
+				"2024-03-01 10:02:00\t *\talice waves",
+			},
+			want: "weechat",
+		},
+		{
+			name: "znc",
+			lines: []string{
+				"[10:00:00] *** Joins: alice (~alice@host)",
+				"[10:01:00] <alice> hello",
+				"[10:02:00] * alice waves",
+			},
+			want: "znc",
+		},
+		{
+			name: "most lines win",
+			lines: []string{
+				"[10:00:00] <alice> hello",
+				"[10:01:00] <bob> hi",
+				"10:02 <carol> hey",
+			},
+			want: "znc",
+		},
+		{
+			name:  "none",
+			lines: []string{"hello", "alice: hi"},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectPreset(tt.lines); got != tt.want {
+				t.Errorf("detectPreset() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportIRC(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		lines []string
+		opts  ImportOptions
+		want  []string // time, kind, user and message of each entry
+	}{
+		{
+			name: "irssi day changes",
+			file: "go.log",
+			lines: []string{
+				"--- Log opened Fri Mar 01 23:58:00 2024",
+				"23:58 <@alice> hello",
+				"23:59  * bob waves",
+				"--- Day changed Sat Mar 02 2024",
+				"00:01 -!- carol [~carol@example.com] has quit [Ping timeout]",
+			},
+			want: []string{
+				"2024-03-01 23:58  alice hello",
+				"2024-03-01 23:59 action bob waves",
+				"2024-03-02 00:01 system  carol has quit [Ping timeout]",
+			},
+		},
+		{
+			name: "znc date from file name and midnight without day change",
+			file: "#go_20240301.log",
+			lines: []string{
+				"[23:59:00] <alice> hello",
+				"[00:01:00] *** Quits: bob (~bob@host) (Remote host closed the connection)",
+			},
+			want: []string{
+				"2024-03-01 23:59  alice hello",
+				"2024-03-02 00:01 system  bob quit (Remote host closed the connection)",
+			},
+		},
+		{
+			name: "custom line pattern",
+			file: "log.txt",
+			lines: []string{
+				"2024-03-01T10:00:00Z alice: hello",
+				"not a message",
+			},
+			opts: ImportOptions{LinePattern: `^(?P<ts>\S+) (?P<user>\w+): (?P<msg>.*)$`},
+			want: []string{"2024-03-01 10:00  alice hello"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(strings.Join(tt.lines, "\n")), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			tt.opts.Location = time.UTC
+			entries, err := ImportIRC(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Timestamp.UTC().Format("2006-01-02 15:04")+" "+string(e.Kind)+" "+e.User+" "+e.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestImportIRCPresetErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(path, []byte("hello\nworld\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportIRC(path, ImportOptions{}); err == nil {
+		t.Error("undetected format: no error")
+	}
+	if _, err := ImportIRC(path, ImportOptions{Preset: "mirc"}); err == nil || !strings.Contains(err.Error(), "irssi") {
+		t.Errorf("unknown preset: error %v does not list the presets", err)
+	}
+}
@@ -0,0 +1,345 @@
+package chatpdf
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ptToMM converts a font size in points to millimetres
+const ptToMM = 25.4 / 72
+
+// lineSpacing is the line height as a multiple of the font size
+const lineSpacing = 1.5
+
+// variationSelector16 requests emoji presentation and is dropped after emoji
+const variationSelector16 = '\uFE0F'
+
+// textRun is a piece of message content: either text or an inline emoji
+// image. Text runs may carry span styles, a link target and a color that
+// replaces the text color, as highlighted code does.
+type textRun struct {
+	text  string
+	image string
+	style SpanStyle
+	url   string
+	color *Color
+}
+
+// fragment is a measured run placed on a line
+type fragment struct {
+	textRun
+	width float64
+}
+
+// line is a row of fragments produced by wrapRuns
+type line struct {
+	fragments []fragment
+	width     float64
+}
+
+// tokenizeMessage splits text into text runs and emoji runs. Any emoji listed
+// in images becomes an image run; everything else stays text.
+func tokenizeMessage(text string, images map[string]string) []textRun {
+	// Longest keys first so multi-rune emoji win over their prefixes
+	keys := make([]string, 0, len(images))
+	for emoji := range images {
+		keys = append(keys, emoji)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	var runs []textRun
+	var buf strings.Builder
+	for i := 0; i < len(text); {
+		matched := ""
+		for _, emoji := range keys {
+			if strings.HasPrefix(text[i:], emoji) {
+				matched = emoji
+				break
+			}
+		}
+		if matched == "" {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			buf.WriteRune(r)
+			i += size
+			continue
+		}
+
+		if buf.Len() > 0 {
+			runs = append(runs, textRun{text: buf.String()})
+			buf.Reset()
+		}
+		runs = append(runs, textRun{image: images[matched]})
+		i += len(matched)
+		if r, size := utf8.DecodeRuneInString(text[i:]); r == variationSelector16 {
+			i += size
+		}
+	}
+	if buf.Len() > 0 {
+		runs = append(runs, textRun{text: buf.String()})
+	}
+	return runs
+}
+
+// splitWords breaks text into alternating words, whitespace and newlines
+func splitWords(text string) []string {
+	var words []string
+	start := 0
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start < i {
+				words = append(words, text[start:i])
+			}
+			words = append(words, string(r))
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+// wrapRuns lays runs out into lines no wider than maxWidth using the current
+// font. Emoji images are sized to the font height and wrap like words.
+func (g *PDFGenerator) wrapRuns(runs []textRun, maxWidth, fontSize float64) []line {
+	emojiSize := fontSize * ptToMM
+	lines := []line{{}}
+
+	for _, r := range runs {
+		if r.image != "" {
+			g.placeFragment(&lines, fragment{textRun: r, width: emojiSize}, maxWidth)
+			continue
+		}
+
+		for _, word := range splitWords(r.text) {
+			cur := &lines[len(lines)-1]
+			run := textRun{text: word, style: r.style, url: r.url}
+			switch {
+			case word == "\n":
+				g.trimTrailingSpace(cur)
+				lines = append(lines, line{})
+			case word == "\r":
+			case isSpace(word):
+				// Spaces are only kept between words on the same line
+				if len(cur.fragments) > 0 {
+					run.text = " "
+					cur.append(fragment{textRun: run, width: g.runWidth(run)})
+				}
+			default:
+				width := g.runWidth(run)
+				if width > maxWidth {
+					g.placeLongWord(&lines, run, maxWidth)
+					continue
+				}
+				g.placeFragment(&lines, fragment{textRun: run, width: width}, maxWidth)
+			}
+		}
+	}
+
+	g.trimTrailingSpace(&lines[len(lines)-1])
+	return lines
+}
+
+// placeFragment appends frag to the last line, starting a new line first
+// when it would not fit
+func (g *PDFGenerator) placeFragment(lines *[]line, frag fragment, maxWidth float64) {
+	cur := &(*lines)[len(*lines)-1]
+	if len(cur.fragments) > 0 && cur.width+frag.width > maxWidth {
+		g.trimTrailingSpace(cur)
+		*lines = append(*lines, line{})
+		cur = &(*lines)[len(*lines)-1]
+	}
+	cur.append(frag)
+}
+
+// placeLongWord breaks a word wider than maxWidth across lines
+func (g *PDFGenerator) placeLongWord(lines *[]line, word textRun, maxWidth float64) {
+	var chunk strings.Builder
+	chunkWidth := 0.0
+	flush := func() {
+		run := word
+		run.text = chunk.String()
+		g.placeFragment(lines, fragment{textRun: run, width: chunkWidth}, maxWidth)
+		chunk.Reset()
+		chunkWidth = 0
+	}
+	for _, r := range word.text {
+		w := g.runWidth(textRun{text: string(r), style: word.style})
+		if chunk.Len() > 0 && chunkWidth+w > maxWidth {
+			flush()
+		}
+		chunk.WriteRune(r)
+		chunkWidth += w
+	}
+	if chunk.Len() > 0 {
+		flush()
+	}
+}
+
+// append adds frag to the line, merging adjacent text fragments of the same
+// style and color
+func (l *line) append(frag fragment) {
+	l.width += frag.width
+	if n := len(l.fragments); n > 0 && frag.image == "" && l.fragments[n-1].image == "" &&
+		frag.style == l.fragments[n-1].style && frag.url == l.fragments[n-1].url &&
+		sameColor(frag.color, l.fragments[n-1].color) {
+		l.fragments[n-1].text += frag.text
+		l.fragments[n-1].width += frag.width
+		return
+	}
+	l.fragments = append(l.fragments, frag)
+}
+
+// sameColor reports whether two optional colors are the same
+func sameColor(a, b *Color) bool {
+	return a == b || a != nil && b != nil && *a == *b
+}
+
+// trimTrailingSpace removes whitespace at the end of the line
+func (g *PDFGenerator) trimTrailingSpace(l *line) {
+	n := len(l.fragments)
+	if n == 0 || l.fragments[n-1].image != "" {
+		return
+	}
+	last := &l.fragments[n-1]
+	trimmed := strings.TrimRight(last.text, " ")
+	if trimmed == last.text {
+		return
+	}
+	l.width -= last.width
+	if trimmed == "" {
+		l.fragments = l.fragments[:n-1]
+		return
+	}
+	last.text = trimmed
+	last.width = g.runWidth(last.textRun)
+	l.width += last.width
+}
+
+// decoration draws behind a block of lines. It is called once for each page
+// the block spans, with the top and height of the part on that page.
+type decoration func(top, height float64)
+
+// drawLines draws wrapped lines starting at the current Y position, moving to
+// a new page whenever a line would run into the footer. When decorate is set,
+// it is drawn behind each page's share of the lines, extended by pad above
+// and below. Y is left below the last line.
+func (g *PDFGenerator) drawLines(lines []line, x, fontSize, pad float64, decorate decoration) {
+	fontHeight := fontSize * ptToMM
+	lineHeight := fontHeight * lineSpacing
+
+	y := g.y
+	for len(lines) > 0 {
+		// Count how many lines fit on this page
+		n := int((g.contentBottom() - y - 2*pad) / lineHeight)
+		if n < 1 {
+			g.newPage()
+			y = g.y
+			continue
+		}
+		if n > len(lines) {
+			n = len(lines)
+		}
+
+		if decorate != nil {
+			decorate(y, float64(n)*lineHeight+2*pad)
+		}
+		y += pad
+
+		for _, l := range lines[:n] {
+			// Center the font's em box vertically in the line
+			baseline := y + (lineHeight-fontHeight)/2 + fontHeight*0.8
+			cx := x
+			for _, frag := range l.fragments {
+				switch {
+				case frag.image != "":
+					top := baseline - fontHeight*0.85
+					g.backend.Image(frag.image, cx, top, frag.width, frag.width)
+				case frag.style != 0:
+					g.drawStyled(frag, cx, baseline, fontHeight)
+				default:
+					g.backend.Text(cx, baseline, frag.text)
+				}
+				cx += frag.width
+			}
+			y += lineHeight
+		}
+
+		y += pad
+		lines = lines[n:]
+	}
+	g.y = y
+}
+
+// drawStyled draws a text fragment with span styles, restoring the font and
+// text color afterwards
+func (g *PDFGenerator) drawStyled(frag fragment, x, baseline, fontHeight float64) {
+	color := g.textColor
+	if frag.style&StyleLink != 0 {
+		color = g.theme.Link
+	}
+	if frag.color != nil {
+		color = *frag.color
+	}
+
+	if frag.style&StyleCode != 0 {
+		g.backend.SetFillColor(g.theme.Meta.mix(g.theme.Background, 0.85))
+		g.backend.Rect(x, baseline-fontHeight*0.85, frag.width, fontHeight*1.1, "F")
+	}
+
+	g.useRunFont(frag.style)
+	g.backend.SetTextColor(color)
+	g.backend.Text(x, baseline, frag.text)
+	g.backend.SetFont(g.fontFamily, g.fontStyle, g.fontSize)
+	g.backend.SetTextColor(g.textColor)
+
+	if frag.style&(StyleUnderline|StyleStrike) != 0 || frag.style&StyleLink != 0 && frag.url != "" {
+		g.backend.SetDrawColor(color)
+		g.backend.SetLineWidth(fontHeight * 0.06)
+		if frag.style&StyleStrike != 0 {
+			y := baseline - fontHeight*0.3
+			g.backend.Line(x, y, x+frag.width, y)
+		}
+		if frag.style&StyleUnderline != 0 || frag.url != "" {
+			y := baseline + fontHeight*0.12
+			g.backend.Line(x, y, x+frag.width, y)
+		}
+	}
+	if frag.url != "" {
+		g.backend.Link(x, baseline-fontHeight*0.8, frag.width, fontHeight, frag.url)
+	}
+}
+
+// runWidth measures a text run in the font for its style
+func (g *PDFGenerator) runWidth(r textRun) float64 {
+	if r.style&(StyleBold|StyleItalic|StyleCode|StyleCodeBlock) == 0 {
+		return g.stringWidth(r.text)
+	}
+	g.useRunFont(r.style)
+	w := g.stringWidth(r.text)
+	g.backend.SetFont(g.fontFamily, g.fontStyle, g.fontSize)
+	return w
+}
+
+// useRunFont selects the font for text in the given span style, on top of
+// the font set with setFont; code is set in the monospace font
+func (g *PDFGenerator) useRunFont(style SpanStyle) {
+	family := g.fontFamily
+	if style&(StyleCode|StyleCodeBlock) != 0 {
+		family = g.monoFamily
+	}
+	g.backend.SetFont(family, fontStyle(g.fontStyle, style), g.fontSize)
+}
+
+// stringWidth measures s in the current font
+func (g *PDFGenerator) stringWidth(s string) float64 {
+	return g.backend.StringWidth(s)
+}
+
+// isSpace reports whether word is a single whitespace character
+func isSpace(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return len(word) == utf8.RuneLen(r) && unicode.IsSpace(r)
+}
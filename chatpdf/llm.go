@@ -0,0 +1,312 @@
+package chatpdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// llmTranscript is an LLM conversation: an OpenAI Chat Completions style
+// messages array, or an Anthropic Messages style request with a top-level
+// system prompt and content blocks
+type llmTranscript struct {
+	Model    string          `json:"model"`
+	System   json.RawMessage `json:"system"`
+	Messages []llmMessage    `json:"messages"`
+}
+
+// llmMessage is one message of a transcript in either style
+type llmMessage struct {
+	Role       string          `json:"role"`
+	Name       string          `json:"name"`
+	Content    json.RawMessage `json:"content"`
+	ToolCallID string          `json:"tool_call_id"`
+	ToolCalls  []struct {
+		ID       string `json:"id"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+	Timestamp recordTime `json:"timestamp"`
+	CreatedAt recordTime `json:"created_at"`
+}
+
+// llmBlock is a content block or content part
+type llmBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+	Source    struct {
+		MediaType string `json:"media_type"`
+	} `json:"source"`
+}
+
+// llmRoleColors gives each role its entry color
+var llmRoleColors = map[string]Color{
+	"system":    {117, 117, 117},
+	"developer": {117, 117, 117},
+	"user":      {30, 136, 229},
+	"assistant": {67, 160, 71},
+	"tool":      {142, 36, 170},
+}
+
+// llmImport collects the entries of a transcript
+type llmImport struct {
+	model   string
+	tools   map[string]string // tool call ID to tool name
+	loc     *time.Location    // zone of times that do not name one
+	entries []ChatEntry
+}
+
+// ImportLLM reads an LLM conversation transcript: a JSON array of messages
+// or an object with a "messages" array, in OpenAI style (string content,
+// tool_calls and tool role messages) or Anthropic style (a top-level system
+// prompt and content blocks with tool_use and tool_result). Each role gets
+// its own color, Markdown in messages becomes spans, tool calls and their
+// results become separate entries, and results get the call's ID as
+// ParentID. Transcripts rarely carry times, so entries have none unless a
+// message has a "timestamp" or "created_at"; times without a zone are read
+// in opts.Location.
+func ImportLLM(path string, opts ImportOptions) ([]ChatEntry, error) {
+	transcript, err := readLLMTranscript(path)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &llmImport{model: transcript.Model, tools: make(map[string]string), loc: opts.location()}
+	if len(transcript.System) > 0 && string(transcript.System) != "null" {
+		blocks, err := llmBlocks(transcript.System)
+		if err != nil {
+			return nil, pathError(path, fmt.Errorf("system: %w", err))
+		}
+		imp.addBlocks(llmMessage{Role: "system"}, blocks)
+	}
+	for i, msg := range transcript.Messages {
+		blocks, err := llmBlocks(msg.Content)
+		if err != nil {
+			return nil, pathError(path, fmt.Errorf("message %d: %w", i+1, err))
+		}
+		imp.addBlocks(msg, blocks)
+		for _, call := range msg.ToolCalls {
+			imp.addToolCall(msg, call.ID, call.Function.Name, call.Function.Arguments)
+		}
+	}
+
+	return opts.Filter(imp.entries), nil
+}
+
+// readLLMTranscript reads a transcript that is either an object with a
+// messages array or a bare array of messages
+func readLLMTranscript(path string) (*llmTranscript, error) {
+	var transcript llmTranscript
+	var raw json.RawMessage
+	if err := readJSONFile(path, &raw); err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimLeft(raw, " \t\r\n")
+	if len(raw) > 0 && raw[0] == '[' {
+		if err := json.Unmarshal(raw, &transcript.Messages); err != nil {
+			return nil, pathError(path, err)
+		}
+		return &transcript, nil
+	}
+	if err := json.Unmarshal(raw, &transcript); err != nil {
+		return nil, pathError(path, err)
+	}
+	if transcript.Messages == nil {
+		return nil, pathError(path, errors.New("no messages array found"))
+	}
+	return &transcript, nil
+}
+
+// llmBlocks decodes message content given as a string, null or an array of
+// content blocks
+func llmBlocks(content json.RawMessage) ([]llmBlock, error) {
+	if len(content) == 0 || string(content) == "null" {
+		return nil, nil
+	}
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		return []llmBlock{{Type: "text", Text: s}}, nil
+	}
+	var blocks []llmBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return nil, errors.New("content must be a string or an array of blocks")
+	}
+	return blocks, nil
+}
+
+// speaker returns the display name for a role
+func (imp *llmImport) speaker(role, name string) string {
+	if name != "" {
+		return name
+	}
+	if role == "assistant" && imp.model != "" {
+		return "Assistant (" + imp.model + ")"
+	}
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// add appends an entry in the style of role
+func (imp *llmImport) add(msg llmMessage, role, user string, text string, spans []Span) *ChatEntry {
+	entry := ChatEntry{
+		Timestamp: msg.Timestamp.in(imp.loc),
+		User:      user,
+		Message:   text,
+		Spans:     spans,
+	}
+	if !msg.Timestamp.set {
+		entry.Timestamp = msg.CreatedAt.in(imp.loc)
+	}
+	if c, ok := llmRoleColors[role]; ok {
+		entry.R, entry.G, entry.B = c.R, c.G, c.B
+	}
+	imp.entries = append(imp.entries, entry)
+	return &imp.entries[len(imp.entries)-1]
+}
+
+// addBlocks adds the content blocks of a message. Consecutive text blocks
+// form one entry; tool calls and tool results get entries of their own.
+func (imp *llmImport) addBlocks(msg llmMessage, blocks []llmBlock) {
+	var text strings.Builder
+	var spans []Span
+	var attachments []Attachment
+	flush := func() {
+		if text.Len() == 0 && len(attachments) == 0 {
+			return
+		}
+		role, user := msg.Role, imp.speaker(msg.Role, msg.Name)
+		parent := ""
+		if msg.Role == "tool" || msg.Role == "function" {
+			role, user, parent = "tool", imp.toolSpeaker(msg.ToolCallID, msg.Name), msg.ToolCallID
+		}
+		entry := imp.add(msg, role, user, text.String(), spans)
+		entry.ParentID = parent
+		entry.Attachments = attachments
+		text.Reset()
+		spans, attachments = nil, nil
+	}
+	paragraph := func() {
+		if text.Len() > 0 {
+			text.WriteString("\n\n")
+		}
+	}
+
+	for _, b := range blocks {
+		switch b.Type {
+		case "text", "input_text", "output_text":
+			paragraph()
+			if msg.Role == "tool" || msg.Role == "function" {
+				text.WriteString(b.Text)
+				continue
+			}
+			start := text.Len()
+			s, md := markdownText(b.Text, markdownCommon)
+			text.WriteString(s)
+			for _, span := range md {
+				span.Start += start
+				span.End += start
+				spans = append(spans, span)
+			}
+		case "thinking":
+			paragraph()
+			start := text.Len()
+			text.WriteString(b.Thinking)
+			spans = append(spans, Span{Start: start, End: text.Len(), Style: StyleItalic})
+		case "image", "image_url", "input_image":
+			name := "Image"
+			if b.Source.MediaType != "" {
+				name += " (" + b.Source.MediaType + ")"
+			}
+			attachments = append(attachments, Attachment{Name: name, MimeType: b.Source.MediaType})
+		case "tool_use":
+			flush()
+			imp.addToolCall(msg, b.ID, b.Name, compactJSON(b.Input))
+		case "tool_result":
+			flush()
+			imp.addToolResult(msg, b)
+		}
+	}
+	flush()
+}
+
+// addToolCall adds an assistant's call of a tool, with its arguments
+func (imp *llmImport) addToolCall(msg llmMessage, id, name, args string) {
+	imp.tools[id] = name
+	text := "Calls " + name
+	spans := []Span{{Start: len("Calls "), End: len(text), Style: StyleBold}}
+	if args != "" && args != "{}" {
+		text += "\n"
+		spans = append(spans, Span{Start: len(text), End: len(text) + len(args), Style: StyleCode})
+		text += args
+	}
+	entry := imp.add(msg, "assistant", imp.speaker("assistant", msg.Name), text, spans)
+	entry.ID = id
+}
+
+// addToolResult adds the result of a tool call given as a content block
+func (imp *llmImport) addToolResult(msg llmMessage, b llmBlock) {
+	blocks, err := llmBlocks(b.Content)
+	var text strings.Builder
+	var spans []Span
+	if b.IsError {
+		text.WriteString("Error")
+		spans = append(spans, Span{End: text.Len(), Style: StyleBold})
+	}
+	if err != nil {
+		// Unknown content shapes are shown as raw JSON
+		blocks = []llmBlock{{Type: "text", Text: compactJSON(b.Content)}}
+	}
+	var attachments []Attachment
+	for _, rb := range blocks {
+		switch rb.Type {
+		case "text":
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(rb.Text)
+		case "image":
+			attachments = append(attachments, Attachment{Name: "Image", MimeType: rb.Source.MediaType})
+		}
+	}
+
+	entry := imp.add(msg, "tool", imp.toolSpeaker(b.ToolUseID, ""), text.String(), spans)
+	entry.ParentID = b.ToolUseID
+	entry.Attachments = attachments
+}
+
+// toolSpeaker names the tool that answered a call
+func (imp *llmImport) toolSpeaker(callID, name string) string {
+	if name == "" {
+		name = imp.tools[callID]
+	}
+	if name == "" {
+		return "Tool"
+	}
+	return "Tool · " + name
+}
+
+// compactJSON returns raw JSON without insignificant whitespace
+func compactJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
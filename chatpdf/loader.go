@@ -0,0 +1,347 @@
+package chatpdf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts lists the accepted textual timestamp formats, tried in order
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// chatRecord is the JSON shape of a single chat message
+type chatRecord struct {
+	Timestamp recordTime   `json:"timestamp"`
+	User      string       `json:"user"`
+	Message   *string      `json:"message"`
+	Color     *recordColor `json:"color"`
+	Icon      string       `json:"icon"`
+	Kind      string       `json:"kind"`
+	ID        recordID     `json:"id"`
+	ParentID  recordID     `json:"parent_id"`
+
+	Attachments []recordAttachment `json:"attachments"`
+}
+
+// recordAttachment is the JSON shape of a file sent with a message
+type recordAttachment struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	URL      string `json:"url"`
+	MimeType string `json:"type"`
+	Size     int64  `json:"size"`
+}
+
+// recordID accepts a message ID given as a string or a number
+type recordID string
+
+func (id *recordID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if data[0] != '"' {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("id must be a string or number")
+		}
+		*id = recordID(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*id = recordID(s)
+	return nil
+}
+
+// recordTime accepts RFC 3339 strings, a few common layouts and Unix seconds
+type recordTime struct {
+	time.Time
+	set      bool
+	zoneless bool // parsed from a layout without a time zone
+}
+
+func (t *recordTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	// Unix timestamps in seconds, possibly fractional
+	if data[0] != '"' {
+		secs, err := strconv.ParseFloat(string(data), 64)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %s", data)
+		}
+		whole := int64(secs)
+		t.Time = time.Unix(whole, int64((secs-float64(whole))*1e9))
+		t.set = true
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			t.set = true
+			t.zoneless = layout != time.RFC3339Nano
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid timestamp %q", s)
+}
+
+// in returns the time, reading a time without a zone as wall clock time in loc
+func (t recordTime) in(loc *time.Location) time.Time {
+	if !t.zoneless {
+		return t.Time
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// recordKinds maps the values of the kind field to entry kinds
+var recordKinds = map[string]EntryKind{
+	"":        KindMessage,
+	"message": KindMessage,
+	"system":  KindSystem,
+	"action":  KindAction,
+}
+
+// recordColor accepts "#rrggbb", "#rgb" or an [r, g, b] array
+type recordColor struct {
+	R, G, B int
+}
+
+func (c *recordColor) UnmarshalJSON(data []byte) error {
+	var rgb []int
+	if err := json.Unmarshal(data, &rgb); err == nil {
+		if len(rgb) != 3 {
+			return fmt.Errorf("color must have 3 components, got %d", len(rgb))
+		}
+		for _, v := range rgb {
+			if v < 0 || v > 255 {
+				return fmt.Errorf("color component %d out of range 0-255", v)
+			}
+		}
+		c.R, c.G, c.B = rgb[0], rgb[1], rgb[2]
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("color must be a hex string or [r, g, b] array")
+	}
+	r, g, b, err := parseHexColor(s)
+	if err != nil {
+		return err
+	}
+	c.R, c.G, c.B = r, g, b
+	return nil
+}
+
+// parseHexColor parses "#rrggbb" or "#rgb" (the leading # is optional)
+func parseHexColor(s string) (r, g, b int, err error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid color %q", s)
+	}
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), nil
+}
+
+// toEntry validates the record and converts it to a ChatEntry, reading
+// times without a zone in loc
+func (rec chatRecord) toEntry(loc *time.Location) (ChatEntry, error) {
+	if !rec.Timestamp.set {
+		return ChatEntry{}, errors.New("missing timestamp")
+	}
+	if rec.Message == nil {
+		return ChatEntry{}, errors.New("missing message")
+	}
+	kind, ok := recordKinds[rec.Kind]
+	if !ok {
+		return ChatEntry{}, fmt.Errorf("unknown kind %q (available: message, system, action)", rec.Kind)
+	}
+
+	entry := ChatEntry{
+		Timestamp: rec.Timestamp.in(loc),
+		User:      rec.User,
+		Message:   *rec.Message,
+		IconPath:  rec.Icon,
+		Kind:      kind,
+		ID:        string(rec.ID),
+		ParentID:  string(rec.ParentID),
+	}
+	if rec.Color != nil {
+		entry.R, entry.G, entry.B = rec.Color.R, rec.Color.G, rec.Color.B
+	}
+	for _, a := range rec.Attachments {
+		if a.Path == "" && a.URL == "" && a.Name == "" {
+			return ChatEntry{}, errors.New("attachment needs a name, path or url")
+		}
+		if a.Name == "" {
+			a.Name = filepath.Base(a.Path)
+			if a.Path == "" {
+				a.Name = a.URL
+			}
+		}
+		if a.Size == 0 && a.Path != "" {
+			if info, err := os.Stat(a.Path); err == nil {
+				a.Size = info.Size()
+			}
+		}
+		entry.Attachments = append(entry.Attachments, Attachment(a))
+	}
+	return entry, nil
+}
+
+// LoadEntries reads chat entries from r, which may hold either a JSON array
+// of messages or newline-delimited JSON (one message object per line).
+// Errors are reported with the line number of the offending message.
+// Timestamps without a time zone are read as local time.
+func LoadEntries(r io.Reader) ([]ChatEntry, error) {
+	return LoadEntriesIn(r, time.Local)
+}
+
+// LoadEntriesIn is LoadEntries with timestamps without a time zone read in
+// loc; nil means local time
+func LoadEntriesIn(r io.Reader, loc *time.Location) ([]ChatEntry, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return loadJSONArray(data, loc)
+	}
+	return loadJSONLines(data, loc)
+}
+
+// LoadEntriesFile reads chat entries from a JSON or JSONL file, reading
+// timestamps without a time zone as local time
+func LoadEntriesFile(path string) ([]ChatEntry, error) {
+	return loadEntriesFile(path, time.Local)
+}
+
+// loadEntriesFile reads a JSON or JSONL file with LoadEntriesIn
+func loadEntriesFile(path string, loc *time.Location) ([]ChatEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := LoadEntriesIn(f, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func loadJSONArray(data []byte, loc *time.Location) ([]ChatEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, &LineError{Line: lineAt(data, dec.InputOffset()), Err: err}
+	}
+
+	var entries []ChatEntry
+	for dec.More() {
+		start := skipSeparators(data, dec.InputOffset())
+
+		var rec chatRecord
+		if err := dec.Decode(&rec); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, &LineError{Line: lineAt(data, syntaxErr.Offset), Err: err}
+			}
+			return nil, &LineError{Line: lineAt(data, start), Err: err}
+		}
+
+		entry, err := rec.toEntry(loc)
+		if err != nil {
+			return nil, &LineError{Line: lineAt(data, start), Err: err}
+		}
+		entries = append(entries, entry)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, &LineError{Line: lineAt(data, dec.InputOffset()), Err: err}
+	}
+	return entries, nil
+}
+
+func loadJSONLines(data []byte, loc *time.Location) ([]ChatEntry, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var entries []ChatEntry
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec chatRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, &LineError{Line: lineNo, Err: err}
+		}
+		entry, err := rec.toEntry(loc)
+		if err != nil {
+			return nil, &LineError{Line: lineNo, Err: err}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// lineAt returns the 1-based line number of the byte offset in data
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// skipSeparators advances offset past whitespace and commas between array elements
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
@@ -0,0 +1,109 @@
+package chatpdf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadEntriesIn(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*3600)
+	tests := []struct {
+		name string
+		in   string
+		want []string // time in UTC, kind, user and message of each entry
+	}{
+		{
+			name: "JSON array",
+			in: `[
+  {"timestamp": "2024-03-01T10:00:00Z", "user": "alice", "message": "hi"},
+  {"timestamp": 1709287260, "user": "bob", "message": "hello", "kind": "message"}
+]`,
+			want: []string{"2024-03-01 10:00:00  alice hi", "2024-03-01 10:01:00  bob hello"},
+		},
+		{
+			name: "JSON lines with a byte order mark and blank lines",
+			in:   "\ufeff" + `{"timestamp": "2024-03-01T10:00:00+01:00", "user": "alice", "message": "hi"}` + "\n\n" + `{"timestamp": "2024-03-01T10:01:00Z", "message": "alice joined", "kind": "system"}` + "\n",
+			want: []string{"2024-03-01 09:00:00  alice hi", "2024-03-01 10:01:00 system  alice joined"},
+		},
+		{
+			name: "zoneless times in the location",
+			in:   `{"timestamp": "2024-03-01 10:00:00", "user": "alice", "message": "hi", "kind": "action"}`,
+			want: []string{"2024-03-01 08:00:00 action alice hi"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := LoadEntriesIn(strings.NewReader(tt.in), zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Timestamp.UTC().Format("2006-01-02 15:04:05")+" "+string(e.Kind)+" "+e.User+" "+e.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLoadEntriesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		line int
+		want string
+	}{
+		{
+			name: "malformed line in JSON lines",
+			in:   `{"timestamp": "2024-03-01T10:00:00Z", "message": "hi"}` + "\n" + `{"timestamp": "2024-03-01T10:01:00Z", "message": }`,
+			line: 2,
+			want: "invalid character",
+		},
+		{
+			name: "malformed message in a JSON array",
+			in:   "[\n" + `{"timestamp": "2024-03-01T10:00:00Z", "message": "hi"},` + "\n" + `{"timestamp": "2024-03-01T10:01:00Z" "message": "hello"}` + "\n]",
+			line: 3,
+			want: "invalid character",
+		},
+		{
+			name: "unknown kind",
+			in:   `{"timestamp": "2024-03-01T10:00:00Z", "message": "hi"}` + "\n" + `{"timestamp": "2024-03-01T10:01:00Z", "message": "hi", "kind": "notice"}`,
+			line: 2,
+			want: `unknown kind "notice" (available: message, system, action)`,
+		},
+		{
+			name: "missing timestamp",
+			in:   "[\n" + `{"message": "hi"}` + "\n]",
+			line: 2,
+			want: "missing timestamp",
+		},
+		{
+			name: "missing message",
+			in:   `{"timestamp": "2024-03-01T10:00:00Z"}`,
+			line: 1,
+			want: "missing message",
+		},
+		{
+			name: "invalid timestamp",
+			in:   `{"timestamp": "yesterday", "message": "hi"}`,
+			line: 1,
+			want: `invalid timestamp "yesterday"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadEntriesIn(strings.NewReader(tt.in), time.UTC)
+			var lineErr *LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("error = %v, want a LineError", err)
+			}
+			if lineErr.Line != tt.line || !strings.Contains(lineErr.Err.Error(), tt.want) {
+				t.Errorf("error = %v, want line %d: %s", err, tt.line, tt.want)
+			}
+		})
+	}
+}
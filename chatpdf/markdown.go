@@ -0,0 +1,409 @@
+package chatpdf
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markdownDialect selects the emphasis markers of a Markdown flavor
+type markdownDialect int
+
+const (
+	markdownCommon  markdownDialect = iota // **bold**, *italic* or _italic_, ~~strike~~
+	markdownSlack                          // Slack's mrkdwn: *bold*, _italic_, ~strike~
+	markdownDiscord                        // as markdownCommon, but __underline__
+)
+
+// markdownDelimiters lists the emphasis markers of each dialect, longest first
+var markdownDelimiters = map[markdownDialect][]struct {
+	marker string
+	style  SpanStyle
+}{
+	markdownCommon: {
+		{"**", StyleBold},
+		{"__", StyleBold},
+		{"~~", StyleStrike},
+		{"*", StyleItalic},
+		{"_", StyleItalic},
+	},
+	markdownSlack: {
+		{"*", StyleBold},
+		{"_", StyleItalic},
+		{"~", StyleStrike},
+	},
+	markdownDiscord: {
+		{"**", StyleBold},
+		{"__", StyleUnderline},
+		{"~~", StyleStrike},
+		{"*", StyleItalic},
+		{"_", StyleItalic},
+	},
+}
+
+// Block level Markdown syntax
+var (
+	markdownFence   = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([^`\\s]*)")
+	markdownHeading = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]+|$)`)
+	markdownClosing = regexp.MustCompile(`(?:^|[ \t]+)#+[ \t]*$`)
+	markdownQuote   = regexp.MustCompile(`^ {0,3}>[ \t]?`)
+	markdownRule    = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	markdownBullet  = regexp.MustCompile(`^([ \t]*)[-*+•][ \t]+`)
+	markdownNumber  = regexp.MustCompile(`^([ \t]*)(\d{1,9})([.)])[ \t]+`)
+	markdownTask    = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+)
+
+// markdownURL matches a bare web address in text
+var markdownURL = regexp.MustCompile(`^https?://[^\s<>]+`)
+
+// markdown builds the plain text and spans of a Markdown message
+type markdown struct {
+	text    strings.Builder
+	spans   []Span
+	dialect markdownDialect
+}
+
+// markdownText converts a Markdown message to plain text with spans: bold,
+// italic, strikethrough, inline code and links become styled text without
+// their markers, headings are bold, block quotes italic, list items get a
+// bullet and fenced code is kept as written in a code block span, with the
+// language from the fence. Lines that are not Markdown are kept as they are.
+func markdownText(s string, dialect markdownDialect) (string, []Span) {
+	md := &markdown{dialect: dialect}
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		if i > 0 {
+			md.text.WriteByte('\n')
+		}
+		line := lines[i]
+
+		if m := markdownFence.FindStringSubmatch(line); m != nil {
+			i = md.codeBlock(lines, i, m[1], m[2])
+			continue
+		}
+		switch {
+		case markdownRule.MatchString(line):
+			md.write("———", 0, "")
+		case markdownHeading.MatchString(line):
+			text := markdownHeading.ReplaceAllString(line, "")
+			md.inline(markdownClosing.ReplaceAllString(text, ""), StyleBold, "")
+		case markdownQuote.MatchString(line):
+			for markdownQuote.MatchString(line) {
+				line = markdownQuote.ReplaceAllString(line, "")
+			}
+			md.inline(line, StyleItalic, "")
+		default:
+			md.listItem(line)
+		}
+	}
+	return md.text.String(), md.spans
+}
+
+// codeBlock writes the lines of a fenced code block starting at lines[start]
+// as a code block in the given language, and returns the index of its
+// closing fence
+func (md *markdown) codeBlock(lines []string, start int, fence, language string) int {
+	end := start + 1
+	for ; end < len(lines); end++ {
+		if m := markdownFence.FindStringSubmatch(lines[end]); m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) {
+			break
+		}
+	}
+	code := strings.Join(lines[start+1:min(end, len(lines))], "\n")
+	if code != "" {
+		from := md.text.Len()
+		md.text.WriteString(code)
+		md.spans = append(md.spans, Span{Start: from, End: md.text.Len(), Style: StyleCodeBlock, Language: language})
+	}
+	return end
+}
+
+// listItem writes a line, replacing the marker of a bulleted list item with
+// a bullet and indenting nested items
+func (md *markdown) listItem(line string) {
+	indent := func(space string) string {
+		width := len(strings.ReplaceAll(space, "\t", "    "))
+		return strings.Repeat("  ", min(width/2, 3))
+	}
+
+	if m := markdownBullet.FindStringSubmatch(line); m != nil {
+		rest := line[len(m[0]):]
+		marker := "• "
+		if t := markdownTask.FindStringSubmatch(rest); t != nil {
+			marker = "☐ "
+			if t[1] != " " {
+				marker = "☑ "
+			}
+			rest = rest[len(t[0]):]
+		}
+		md.write(indent(m[1])+marker, 0, "")
+		md.inline(rest, 0, "")
+		return
+	}
+	if m := markdownNumber.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		md.write(indent(m[1])+strconv.Itoa(n)+". ", 0, "")
+		md.inline(line[len(m[0]):], 0, "")
+		return
+	}
+	md.inline(line, 0, "")
+}
+
+// write appends text in a style, linked to url when it is set
+func (md *markdown) write(s string, style SpanStyle, url string) {
+	if s == "" {
+		return
+	}
+	start := md.text.Len()
+	md.text.WriteString(s)
+	if style != 0 {
+		md.spans = append(md.spans, Span{Start: start, End: md.text.Len(), Style: style, URL: url})
+	}
+}
+
+// inline writes a line of text, turning emphasis, code spans and links into
+// spans on top of the given style
+func (md *markdown) inline(s string, style SpanStyle, url string) {
+	var plain strings.Builder
+	flush := func() {
+		md.write(plain.String(), style, url)
+		plain.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(markdownEscapable, s[i+1]) >= 0:
+			plain.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			if code, n, ok := codeSpan(s[i:]); ok {
+				flush()
+				md.write(code, style|StyleCode, url)
+				i += n
+				continue
+			}
+			n := runLength(s[i:], '`')
+			plain.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case (c == '[' || c == '!') && url == "":
+			if label, target, n, ok := markdownLink(s[i:]); ok {
+				flush()
+				if c == '!' {
+					// Images are shown as their description
+					label = "Image: " + label
+				}
+				md.inline(label, style|StyleLink, target)
+				i += n
+				continue
+			}
+
+		case c == '<' && url == "":
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				target := s[i+1 : i+end]
+				if markdownURL.MatchString(target) || strings.HasPrefix(target, "mailto:") {
+					flush()
+					md.write(strings.TrimPrefix(target, "mailto:"), style|StyleLink, target)
+					i += end + 1
+					continue
+				}
+			}
+
+		case c == 'h' && url == "" && (i == 0 || !isWordRune(lastRune(s[:i]))):
+			if m := markdownURL.FindString(s[i:]); m != "" {
+				target := trimURL(m)
+				flush()
+				md.write(target, style|StyleLink, target)
+				i += len(target)
+				continue
+			}
+		}
+
+		if marker, delimStyle, ok := md.opener(s, i); ok {
+			if end := md.closer(s, i+len(marker), marker); end >= 0 {
+				flush()
+				md.inline(s[i+len(marker):end], style|delimStyle, url)
+				i = end + len(marker)
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		plain.WriteString(s[i : i+size])
+		i += size
+	}
+	flush()
+}
+
+// markdownEscapable lists the characters a backslash makes literal
+const markdownEscapable = "\\`*_{}[]()#+-.!~<>|"
+
+// opener returns the emphasis marker starting at s[i], if any. A marker
+// must be followed by text, and underscores and Slack's tildes must not be
+// inside a word, so snake_case names are left alone.
+func (md *markdown) opener(s string, i int) (marker string, style SpanStyle, ok bool) {
+	for _, d := range markdownDelimiters[md.dialect] {
+		if !strings.HasPrefix(s[i:], d.marker) {
+			continue
+		}
+		next := i + len(d.marker)
+		if next >= len(s) || unicode.IsSpace(firstRune(s[next:])) {
+			continue
+		}
+		if md.wordBound(d.marker) && i > 0 && isWordRune(lastRune(s[:i])) {
+			continue
+		}
+		return d.marker, d.style, true
+	}
+	return "", 0, false
+}
+
+// closer returns the offset of the marker that closes emphasis opened
+// before from, or -1. Code spans are skipped, and a single character marker
+// is not closed by a doubled one, which belongs to nested emphasis.
+func (md *markdown) closer(s string, from int, marker string) int {
+	c := marker[0]
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			if _, n, ok := codeSpan(s[j:]); ok {
+				j += n
+				continue
+			}
+		case c:
+			run := runLength(s[j:], c)
+			end := j + run
+			switch {
+			case j == from || unicode.IsSpace(lastRune(s[:j])):
+			case run < len(marker), len(marker) == 1 && run == 2:
+			case md.wordBound(marker) && end < len(s) && isWordRune(firstRune(s[end:])):
+			default:
+				return end - len(marker)
+			}
+			j = end
+			continue
+		}
+		j++
+	}
+	return -1
+}
+
+// wordBound reports whether a marker only counts at the edge of a word
+func (md *markdown) wordBound(marker string) bool {
+	return marker[0] == '_' || (md.dialect == markdownSlack && marker[0] == '~')
+}
+
+// codeSpan returns the content of a code span at the start of s and its
+// length in s; the closing backticks must match the opening ones in number
+func codeSpan(s string) (code string, n int, ok bool) {
+	ticks := runLength(s, '`')
+	for j := ticks; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			break
+		}
+		j += k
+		run := runLength(s[j:], '`')
+		if run == ticks {
+			code = s[ticks:j]
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			return code, j + run, true
+		}
+		j += run
+	}
+	return "", 0, false
+}
+
+// markdownLink parses a [label](url) link or ![alt](url) image at the start
+// of s, returning its length in s
+func markdownLink(s string) (label, url string, n int, ok bool) {
+	start := strings.IndexByte(s, '[')
+	if start < 0 || start > 1 {
+		return "", "", 0, false
+	}
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if i+1 >= len(s) || s[i+1] != '(' {
+				return "", "", 0, false
+			}
+			end := strings.IndexByte(s[i+2:], ')')
+			if end < 0 {
+				return "", "", 0, false
+			}
+			target := strings.TrimSpace(s[i+2 : i+2+end])
+			// Drop a link title, as in [label](url "title")
+			if sp := strings.IndexAny(target, " \t"); sp >= 0 {
+				target = target[:sp]
+			}
+			target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			if target == "" {
+				return "", "", 0, false
+			}
+			return s[start+1 : i], target, i + 3 + end, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// trimURL drops punctuation that ends the sentence around a bare URL, and
+// closing parentheses without an opening one in the URL
+func trimURL(url string) string {
+	for len(url) > 0 {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"*_~", last) >= 0:
+		case last == ')' && strings.Count(url, "(") < strings.Count(url, ")"):
+		default:
+			return url
+		}
+		url = url[:len(url)-1]
+	}
+	return url
+}
+
+// runLength counts the leading bytes of s equal to c
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// firstRune returns the first rune of s
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// lastRune returns the last rune of s
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// isWordRune reports whether r is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
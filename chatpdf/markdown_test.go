@@ -0,0 +1,158 @@
+package chatpdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// spanList describes spans as the text they cover with their style number,
+// URL and language, for comparing in tests
+func spanList(text string, spans []Span) string {
+	var parts []string
+	for _, s := range spans {
+		part := fmt.Sprintf("%q %d", text[s.Start:s.End], s.Style)
+		if s.URL != "" {
+			part += " " + s.URL
+		}
+		if s.Language != "" {
+			part += " " + s.Language
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func TestMarkdownText(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		dialect markdownDialect
+		text    string
+		spans   string
+	}{
+		{
+			name:  "bold and italic",
+			in:    "**bold** and *italic* and _also_",
+			text:  "bold and italic and also",
+			spans: `"bold" 1, "italic" 2, "also" 2`,
+		},
+		{
+			name:  "nested emphasis",
+			in:    "**bold *both* bold**",
+			text:  "bold both bold",
+			spans: `"bold " 1, "both" 3, " bold" 1`,
+		},
+		{
+			name: "underscores inside words",
+			in:   "call snake_case_name or __init__",
+			text: "call snake_case_name or init",
+			// __init__ is bold at word edges
+			spans: `"init" 1`,
+		},
+		{
+			name:  "markers followed by a space",
+			in:    "2 * 3 * 4 and a_ b_",
+			text:  "2 * 3 * 4 and a_ b_",
+			spans: "",
+		},
+		{
+			name:  "unclosed marker",
+			in:    "**not bold",
+			text:  "**not bold",
+			spans: "",
+		},
+		{
+			name:  "escaped markers",
+			in:    `\*literal\* and \_this\_`,
+			text:  "*literal* and _this_",
+			spans: "",
+		},
+		{
+			name:  "code spans hide markers",
+			in:    "run `a*b*c` now",
+			text:  "run a*b*c now",
+			spans: `"a*b*c" 16`,
+		},
+		{
+			name:  "double backtick code span",
+			in:    "``a ` b``",
+			text:  "a ` b",
+			spans: `"a ` + "`" + ` b" 16`,
+		},
+		{
+			name:  "strikethrough",
+			in:    "~~gone~~",
+			text:  "gone",
+			spans: `"gone" 8`,
+		},
+		{
+			name:    "Slack emphasis",
+			in:      "*bold* _italic_ ~strike~ a~b~c",
+			dialect: markdownSlack,
+			text:    "bold italic strike a~b~c",
+			spans:   `"bold" 1, "italic" 2, "strike" 8`,
+		},
+		{
+			name:    "Discord underline",
+			in:      "__under__ **bold**",
+			dialect: markdownDiscord,
+			text:    "under bold",
+			spans:   `"under" 4, "bold" 1`,
+		},
+		{
+			name:  "links",
+			in:    "[site](https://example.com) and https://go.dev/doc. and <mailto:a@b.c>",
+			text:  "site and https://go.dev/doc. and a@b.c",
+			spans: `"site" 32 https://example.com, "https://go.dev/doc" 32 https://go.dev/doc, "a@b.c" 32 mailto:a@b.c`,
+		},
+		{
+			name:  "fence with language",
+			in:    "look:\n```go\nx := *p\n```\ndone",
+			text:  "look:\nx := *p\ndone",
+			spans: `"x := *p" 64 go`,
+		},
+		{
+			name:  "tilde fence closed by a longer fence only",
+			in:    "~~~~\na\n~~~\nb\n~~~~~",
+			text:  "a\n~~~\nb",
+			spans: `"a\n~~~\nb" 64`,
+		},
+		{
+			name:  "unclosed fence runs to the end",
+			in:    "```python\nprint(1)",
+			text:  "print(1)",
+			spans: `"print(1)" 64 python`,
+		},
+		{
+			name:  "backtick fence not closed by tildes",
+			in:    "```\na\n~~~\n```",
+			text:  "a\n~~~",
+			spans: `"a\n~~~" 64`,
+		},
+		{
+			name:  "lists",
+			in:    "- one\n  * nested\n3) three\n- [x] done\n- [ ] todo",
+			text:  "• one\n  • nested\n3. three\n☑ done\n☐ todo",
+			spans: "",
+		},
+		{
+			name:  "headings, quotes and rules",
+			in:    "## Title ##\n> > quoted\n---",
+			text:  "Title\nquoted\n———",
+			spans: `"Title" 1, "quoted" 2`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, spans := markdownText(tt.in, tt.dialect)
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+				return
+			}
+			if got := spanList(text, spans); got != tt.spans {
+				t.Errorf("spans = %s, want %s", got, tt.spans)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package chatpdf
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// matrixExport is a room exported by Element as JSON
+type matrixExport struct {
+	RoomName string        `json:"room_name"`
+	RoomID   string        `json:"room_id"`
+	Messages []matrixEvent `json:"messages"`
+}
+
+// matrixEvent is a room event: a message, a state change such as a member
+// joining, or a relation to another event such as an edit or reaction
+type matrixEvent struct {
+	Type           string        `json:"type"`
+	EventID        string        `json:"event_id"`
+	Sender         string        `json:"sender"`
+	StateKey       *string       `json:"state_key"`
+	OriginServerTS int64         `json:"origin_server_ts"`
+	Redacts        string        `json:"redacts"`
+	Content        matrixContent `json:"content"`
+	Unsigned       struct {
+		RedactedBecause *matrixEvent   `json:"redacted_because"`
+		PrevContent     *matrixContent `json:"prev_content"`
+	} `json:"unsigned"`
+}
+
+// matrixContent is the content of an event. Only the fields used for the
+// event types shown in the PDF are decoded.
+type matrixContent struct {
+	MsgType       string         `json:"msgtype"`
+	Body          string         `json:"body"`
+	Format        string         `json:"format"`
+	FormattedBody string         `json:"formatted_body"`
+	URL           string         `json:"url"`
+	FileName      string         `json:"filename"`
+	Info          *matrixInfo    `json:"info"`
+	NewContent    *matrixContent `json:"m.new_content"`
+	RelatesTo     *struct {
+		RelType   string `json:"rel_type"`
+		EventID   string `json:"event_id"`
+		Key       string `json:"key"`
+		InReplyTo *struct {
+			EventID string `json:"event_id"`
+		} `json:"m.in_reply_to"`
+	} `json:"m.relates_to"`
+	Reason      string `json:"reason"`
+	Membership  string `json:"membership"`
+	DisplayName string `json:"displayname"`
+	Name        string `json:"name"`
+	Topic       string `json:"topic"`
+}
+
+// matrixInfo describes the file of a media message
+type matrixInfo struct {
+	MimeType string `json:"mimetype"`
+	Size     int64  `json:"size"`
+}
+
+// matrixRoom collects what later events say about earlier ones
+type matrixRoom struct {
+	names     map[string]string         // user ID to display name at the current event
+	edits     map[string]*matrixContent // event ID to its latest content
+	redacted  map[string]string         // event ID to the reason it was redacted
+	reactions map[string][]Reaction     // event ID to its reactions
+}
+
+// ImportMatrix reads a Matrix room exported by Element in JSON format.
+// Edits replace the text of the message they change, which is marked
+// "(edited)"; redacted messages are kept and marked as deleted, with the
+// reason when one was given; replies and thread messages get the parent's
+// ID as ParentID; emotes become KindAction entries and membership and room
+// changes KindSystem entries. Formatted bodies keep their formatting. Edits
+// by anyone but the original sender are ignored, and senders are named by
+// the display name they had when they sent each event.
+func ImportMatrix(path string, opts ImportOptions) ([]ChatEntry, error) {
+	var export matrixExport
+	if err := readJSONFile(path, &export); err != nil {
+		return nil, err
+	}
+	if opts.Channel != "" && !strings.EqualFold(opts.Channel, export.RoomName) && opts.Channel != export.RoomID {
+		return nil, &OptionError{Option: "Channel", Value: opts.Channel, Valid: []string{export.RoomName}}
+	}
+
+	mediaDir := opts.mediaDir(path)
+	events := append([]matrixEvent(nil), export.Messages...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OriginServerTS < events[j].OriginServerTS })
+	room := newMatrixRoom(events)
+
+	var entries []ChatEntry
+	for _, ev := range events {
+		entry, ok := room.entry(ev, mediaDir)
+		if ok && opts.inRange(entry.Timestamp) {
+			entries = append(entries, entry)
+		}
+		room.track(ev)
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// newMatrixRoom collects the edits, redactions and reactions of a room's
+// events, which are in time order, and each member's display name before
+// their first membership event
+func newMatrixRoom(events []matrixEvent) *matrixRoom {
+	room := &matrixRoom{
+		names:     make(map[string]string),
+		edits:     make(map[string]*matrixContent),
+		redacted:  make(map[string]string),
+		reactions: make(map[string][]Reaction),
+	}
+	senders := make(map[string]string)
+	for _, ev := range events {
+		senders[ev.EventID] = ev.Sender
+		if ev.Unsigned.RedactedBecause != nil {
+			room.redacted[ev.EventID] = ev.Unsigned.RedactedBecause.Content.Reason
+		}
+		if ev.Type == "m.room.redaction" && ev.Redacts != "" {
+			room.redacted[ev.Redacts] = ev.Content.Reason
+		}
+	}
+
+	for _, ev := range events {
+		if _, ok := room.redacted[ev.EventID]; ok && ev.Type != "m.room.message" {
+			continue
+		}
+		rel := ev.Content.RelatesTo
+		switch {
+		case ev.Type == "m.room.member" && ev.StateKey != nil:
+			// Messages before a member's first membership event in the
+			// export were sent under the name it replaced
+			if _, seen := room.names[*ev.StateKey]; !seen {
+				name := ev.Content.DisplayName
+				if prev := ev.Unsigned.PrevContent; prev != nil && prev.DisplayName != "" {
+					name = prev.DisplayName
+				}
+				room.names[*ev.StateKey] = name
+			}
+		case ev.Type == "m.reaction" && rel != nil && rel.RelType == "m.annotation":
+			room.addReaction(rel.EventID, rel.Key)
+		case ev.Type == "m.room.message" && rel != nil && rel.RelType == "m.replace" && ev.Content.NewContent != nil:
+			// Only the sender of a message may edit it
+			if _, ok := room.redacted[ev.EventID]; !ok && ev.Sender == senders[rel.EventID] {
+				room.edits[rel.EventID] = ev.Content.NewContent
+			}
+		}
+	}
+	return room
+}
+
+// track updates the display names after a membership event, so later
+// events are named by the member's new name
+func (room *matrixRoom) track(ev matrixEvent) {
+	if ev.Type != "m.room.member" || ev.StateKey == nil {
+		return
+	}
+	if _, ok := room.redacted[ev.EventID]; ok {
+		return
+	}
+	if ev.Content.Membership == "join" || ev.Content.DisplayName != "" {
+		room.names[*ev.StateKey] = ev.Content.DisplayName
+	}
+}
+
+// addReaction counts a reaction to an event
+func (room *matrixRoom) addReaction(id, key string) {
+	reactions := room.reactions[id]
+	for i := range reactions {
+		if reactions[i].Emoji == key {
+			reactions[i].Count++
+			return
+		}
+	}
+	room.reactions[id] = append(reactions, Reaction{Emoji: key, Count: 1})
+}
+
+// name returns the display name of a user, or the local part of their ID
+func (room *matrixRoom) name(id string) string {
+	if name := room.names[id]; name != "" {
+		return name
+	}
+	local, _, _ := strings.Cut(strings.TrimPrefix(id, "@"), ":")
+	return local
+}
+
+// entry converts an event to a chat entry; ok is false for events that are
+// not shown on their own, such as edits and reactions
+func (room *matrixRoom) entry(ev matrixEvent, mediaDir string) (entry ChatEntry, ok bool) {
+	entry = ChatEntry{
+		Timestamp: time.UnixMilli(ev.OriginServerTS),
+		User:      room.name(ev.Sender),
+		ID:        ev.EventID,
+	}
+
+	switch ev.Type {
+	case "m.room.message", "m.room.encrypted", "m.sticker":
+	case "m.room.member", "m.room.name", "m.room.topic", "m.room.create":
+		entry.Kind = KindSystem
+		entry.User = ""
+		entry.Message = room.stateText(ev)
+		return entry, entry.Message != ""
+	default:
+		return entry, false
+	}
+
+	rel := ev.Content.RelatesTo
+	if rel != nil {
+		switch {
+		case rel.RelType == "m.replace":
+			return entry, false
+		case rel.RelType == "m.thread":
+			entry.ParentID = rel.EventID
+		case rel.InReplyTo != nil:
+			entry.ParentID = rel.InReplyTo.EventID
+		}
+	}
+
+	if reason, ok := room.redacted[ev.EventID]; ok {
+		markDeleted(&entry, reason)
+		return entry, true
+	}
+	if ev.Type == "m.room.encrypted" {
+		entry.Message = "Unable to decrypt message"
+		entry.Spans = []Span{{End: len(entry.Message), Style: StyleItalic}}
+		return entry, true
+	}
+
+	content := &ev.Content
+	edited := room.edits[ev.EventID]
+	if edited != nil {
+		content = edited
+	}
+	if ev.Type == "m.sticker" {
+		entry.Attachments = []Attachment{{Name: "Sticker " + content.Body}}
+		entry.Reactions = room.reactions[ev.EventID]
+		return entry, true
+	}
+
+	switch content.MsgType {
+	case "m.image", "m.file", "m.video", "m.audio":
+		name := content.FileName
+		if name == "" {
+			name = content.Body
+		}
+		a := mediaAttachment(mediaDir, name)
+		if a.Path == "" {
+			a.URL = content.URL
+		}
+		if content.Info != nil {
+			if a.MimeType == "" {
+				a.MimeType = content.Info.MimeType
+			}
+			if a.Size == 0 {
+				a.Size = content.Info.Size
+			}
+		}
+		entry.Attachments = []Attachment{a}
+		if content.FileName != "" && content.Body != content.FileName {
+			// A caption
+			entry.Message = content.Body
+		}
+	default:
+		entry.Message, entry.Spans = matrixText(content, entry.ParentID != "")
+		if content.MsgType == "m.emote" {
+			entry.Kind = KindAction
+		}
+	}
+
+	if edited != nil {
+		start := len(entry.Message)
+		entry.Message += " (edited)"
+		entry.Spans = append(entry.Spans, Span{Start: start + 1, End: len(entry.Message), Style: StyleItalic})
+	}
+	entry.Reactions = room.reactions[ev.EventID]
+	return entry, true
+}
+
+// matrixText returns the text of a message with its formatting, leaving
+// out the quote of the parent that clients put at the start of replies
+func matrixText(content *matrixContent, reply bool) (string, []Span) {
+	if content.Format == "org.matrix.custom.html" && content.FormattedBody != "" {
+		root := parseHTML(content.FormattedBody)
+		for _, quote := range root.findAll(func(n *htmlNode) bool { return n.tag == "mx-reply" }) {
+			quote.remove()
+		}
+		return nodeText(root)
+	}
+
+	body := content.Body
+	if reply && strings.HasPrefix(body, "> ") {
+		lines := strings.Split(body, "\n")
+		i := 0
+		for i < len(lines) && strings.HasPrefix(lines[i], ">") {
+			i++
+		}
+		if i < len(lines) && lines[i] == "" {
+			body = strings.Join(lines[i+1:], "\n")
+		}
+	}
+	return body, nil
+}
+
+// stateText describes a membership or room change, or returns "" for
+// changes not worth showing
+func (room *matrixRoom) stateText(ev matrixEvent) string {
+	who := room.name(ev.Sender)
+	switch ev.Type {
+	case "m.room.create":
+		return who + " created the room"
+	case "m.room.name":
+		return who + " changed the room name to \"" + ev.Content.Name + "\""
+	case "m.room.topic":
+		return who + " changed the topic to \"" + ev.Content.Topic + "\""
+	}
+
+	target := who
+	if ev.StateKey != nil {
+		target = ev.Content.DisplayName
+		if target == "" {
+			target = room.name(*ev.StateKey)
+		}
+	}
+	self := ev.StateKey == nil || *ev.StateKey == ev.Sender
+	var text string
+	prev := ev.Unsigned.PrevContent
+	switch ev.Content.Membership {
+	case "join":
+		if prev != nil && prev.Membership == "join" {
+			// A profile change; avatar changes are not shown
+			if prev.DisplayName == "" || prev.DisplayName == ev.Content.DisplayName {
+				return ""
+			}
+			return prev.DisplayName + " changed their name to " + target
+		}
+		text = target + " joined"
+	case "invite":
+		text = who + " invited " + target
+	case "leave":
+		text = target + " left"
+		if !self {
+			text = who + " removed " + target
+		}
+	case "ban":
+		text = who + " banned " + target
+	default:
+		return ""
+	}
+	if ev.Content.Reason != "" {
+		text += " (" + ev.Content.Reason + ")"
+	}
+	return text
+}
@@ -0,0 +1,63 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportMatrix(t *testing.T) {
+	tests := []struct {
+		name   string
+		events string
+		want   []string // kind, user and message of each entry
+	}{
+		{
+			name: "edits by the sender only",
+			events: `
+{"type": "m.room.message", "event_id": "$1", "sender": "@al:x", "origin_server_ts": 1000, "content": {"msgtype": "m.text", "body": "first"}},
+{"type": "m.room.message", "event_id": "$2", "sender": "@eve:x", "origin_server_ts": 2000, "content": {"msgtype": "m.text", "body": "* hacked", "m.new_content": {"msgtype": "m.text", "body": "hacked"}, "m.relates_to": {"rel_type": "m.replace", "event_id": "$1"}}},
+{"type": "m.room.message", "event_id": "$3", "sender": "@al:x", "origin_server_ts": 3000, "content": {"msgtype": "m.text", "body": "second"}},
+{"type": "m.room.message", "event_id": "$4", "sender": "@eve:x", "origin_server_ts": 4000, "content": {"msgtype": "m.text", "body": "* hacked", "m.new_content": {"msgtype": "m.text", "body": "hacked"}, "m.relates_to": {"rel_type": "m.replace", "event_id": "$3"}}},
+{"type": "m.room.message", "event_id": "$5", "sender": "@al:x", "origin_server_ts": 5000, "content": {"msgtype": "m.text", "body": "* second!", "m.new_content": {"msgtype": "m.text", "body": "second!"}, "m.relates_to": {"rel_type": "m.replace", "event_id": "$3"}}}`,
+			want: []string{" al first", " al second! (edited)"},
+		},
+		{
+			name: "display name change partway through",
+			events: `
+{"type": "m.room.message", "event_id": "$1", "sender": "@al:x", "origin_server_ts": 1000, "content": {"msgtype": "m.text", "body": "before"}},
+{"type": "m.room.member", "event_id": "$2", "sender": "@al:x", "state_key": "@al:x", "origin_server_ts": 2000, "content": {"membership": "join", "displayname": "Alice"}, "unsigned": {"prev_content": {"membership": "join", "displayname": "Al"}}},
+{"type": "m.room.message", "event_id": "$4", "sender": "@al:x", "origin_server_ts": 4000, "content": {"msgtype": "m.emote", "body": "waves"}},
+{"type": "m.room.message", "event_id": "$3", "sender": "@al:x", "origin_server_ts": 3000, "content": {"msgtype": "m.text", "body": "after"}}`,
+			want: []string{" Al before", "system  Al changed their name to Alice", " Alice after", "action Alice waves"},
+		},
+		{
+			name: "redacted message",
+			events: `
+{"type": "m.room.message", "event_id": "$1", "sender": "@al:x", "origin_server_ts": 1000, "content": {"msgtype": "m.text", "body": "oops"}},
+{"type": "m.room.redaction", "event_id": "$2", "sender": "@al:x", "redacts": "$1", "origin_server_ts": 2000, "content": {"reason": "typo"}}`,
+			want: []string{" al This message was deleted (typo)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "room.json")
+			data := `{"room_name": "Room", "room_id": "!r:x", "messages": [` + tt.events + `]}`
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := ImportMatrix(path, ImportOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, string(e.Kind)+" "+e.User+" "+e.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package chatpdf
+
+import (
+	"regexp"
+	"strings"
+)
+
+// shortcodes maps common :name: emoji shortcodes, as used by Slack and
+// other chat services, to the emoji they stand for
+var shortcodes = map[string]string{
+	"+1":                    "👍",
+	"thumbsup":              "👍",
+	"-1":                    "👎",
+	"thumbsdown":            "👎",
+	"heart":                 "❤️",
+	"smile":                 "😄",
+	"slightly_smiling_face": "🙂",
+	"laughing":              "😆",
+	"joy":                   "😂",
+	"sob":                   "😭",
+	"wink":                  "😉",
+	"thinking_face":         "🤔",
+	"tada":                  "🎉",
+	"eyes":                  "👀",
+	"fire":                  "🔥",
+	"pray":                  "🙏",
+	"clap":                  "👏",
+	"raised_hands":          "🙌",
+	"ok_hand":               "👌",
+	"wave":                  "👋",
+	"100":                   "💯",
+	"white_check_mark":      "✅",
+	"heavy_check_mark":      "✔️",
+	"x":                     "❌",
+	"warning":               "⚠️",
+	"rocket":                "🚀",
+	"dart":                  "🎯",
+	"books":                 "📚",
+	"bulb":                  "💡",
+}
+
+// shortcodeEmoji returns the emoji for a shortcode name, ignoring skin tone
+// modifiers, or the shortcode itself in colons when it is not known
+func shortcodeEmoji(name string) string {
+	name, _, _ = strings.Cut(name, "::")
+	if emoji, ok := shortcodes[name]; ok {
+		return emoji
+	}
+	return ":" + name + ":"
+}
+
+// shortcodePattern matches :name: shortcodes with an optional skin tone
+var shortcodePattern = regexp.MustCompile(`:([a-z0-9_+\-]+):(?::skin-tone-\d:)?`)
+
+// replaceShortcodes replaces the known shortcodes in s with their emoji
+func replaceShortcodes(s string) string {
+	return shortcodePattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := shortcodePattern.FindStringSubmatch(m)[1]
+		if emoji, ok := shortcodes[name]; ok {
+			return emoji
+		}
+		return m
+	})
+}
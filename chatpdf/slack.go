@@ -0,0 +1,406 @@
+package chatpdf
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackProfile holds the names a Slack user chose for themselves
+type slackProfile struct {
+	DisplayName string `json:"display_name"`
+	RealName    string `json:"real_name"`
+}
+
+// slackUser is the part of a users.json record used to resolve names
+type slackUser struct {
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	RealName string       `json:"real_name"`
+	Profile  slackProfile `json:"profile"`
+}
+
+// displayName picks the name Slack itself would show for the user
+func (u slackUser) displayName() string {
+	for _, name := range []string{u.Profile.DisplayName, u.Profile.RealName, u.RealName, u.Name} {
+		if name != "" {
+			return name
+		}
+	}
+	return u.ID
+}
+
+// slackChannel is the part of a channels.json record used to find messages
+type slackChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// slackFile is a file shared in a message
+type slackFile struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Title      string `json:"title"`
+	Mimetype   string `json:"mimetype"`
+	Size       int64  `json:"size"`
+	URLPrivate string `json:"url_private"`
+}
+
+// slackReaction is an emoji reaction, named by its shortcode
+type slackReaction struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// slackBotProfile names the app that posted a bot message
+type slackBotProfile struct {
+	Name string `json:"name"`
+}
+
+// slackMessage is one message of a per-day export file
+type slackMessage struct {
+	Type        string           `json:"type"`
+	Subtype     string           `json:"subtype"`
+	User        string           `json:"user"`
+	Username    string           `json:"username"`
+	UserProfile *slackProfile    `json:"user_profile"`
+	BotID       string           `json:"bot_id"`
+	BotProfile  *slackBotProfile `json:"bot_profile"`
+	Text        string           `json:"text"`
+	TS          string           `json:"ts"`
+	ThreadTS    string           `json:"thread_ts"`
+	DeletedTS   string           `json:"deleted_ts"`
+	Files       []slackFile      `json:"files"`
+	Reactions   []slackReaction  `json:"reactions"`
+}
+
+// slackEvents lists the message subtypes that are channel events rather
+// than messages, with the text shown when the event has none
+var slackEvents = map[string]string{
+	"channel_join":      "joined the channel",
+	"channel_leave":     "left the channel",
+	"channel_topic":     "changed the channel topic",
+	"channel_purpose":   "changed the channel purpose",
+	"channel_name":      "renamed the channel",
+	"channel_archive":   "archived the channel",
+	"channel_unarchive": "unarchived the channel",
+	"group_join":        "joined the channel",
+	"group_leave":       "left the channel",
+	"group_topic":       "changed the channel topic",
+	"group_purpose":     "changed the channel purpose",
+	"pinned_item":       "pinned a message",
+	"unpinned_item":     "unpinned a message",
+}
+
+// slackExport is an unzipped Slack workspace export
+type slackExport struct {
+	users    map[string]string // user ID to display name
+	channels []slackChannel
+	mediaDir string // where downloaded files are looked for
+}
+
+// ImportSlack reads one channel of an unzipped Slack workspace export: the
+// directory holding channels.json, users.json and a folder of per-day
+// message files for each channel. User IDs are resolved to display names,
+// mrkdwn formatting becomes spans, thread replies get the parent's ID as
+// ParentID, and shared files and reactions are kept with their message.
+// Channel events such as members joining become KindSystem entries, bot
+// messages are named after their bot, and deleted messages are marked.
+// Slack's own exports only link to shared files; files downloaded next to
+// the export by tools such as slackdump are found by their file ID and name.
+func ImportSlack(dir string, opts ImportOptions) ([]ChatEntry, error) {
+	export, err := openSlackExport(dir)
+	if err != nil {
+		return nil, err
+	}
+	channel, err := export.channel(opts.Channel)
+	if err != nil {
+		return nil, err
+	}
+	export.mediaDir = opts.mediaDir(dir)
+
+	days, err := filepath.Glob(filepath.Join(dir, channel.Name, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(days)
+
+	var entries []ChatEntry
+	deleted := make(map[string]bool)
+	for _, day := range days {
+		var messages []slackMessage
+		if err := readJSONFile(day, &messages); err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			if msg.Type != "" && msg.Type != "message" {
+				continue
+			}
+			if msg.Subtype == "message_deleted" {
+				// The event only names the message it deleted
+				deleted[msg.DeletedTS] = true
+				continue
+			}
+			entry, err := export.entry(channel.Name, msg)
+			if err != nil {
+				return nil, pathError(day, err)
+			}
+			if opts.inRange(entry.Timestamp) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	for i := range entries {
+		if deleted[entries[i].ID] {
+			markDeleted(&entries[i], "")
+		}
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// openSlackExport reads the user and channel lists of an export
+func openSlackExport(dir string) (*slackExport, error) {
+	export := &slackExport{users: make(map[string]string)}
+
+	var users []slackUser
+	if err := readJSONFile(filepath.Join(dir, "users.json"), &users); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, u := range users {
+		export.users[u.ID] = u.displayName()
+	}
+
+	// Private channels are listed in groups.json
+	for _, name := range []string{"channels.json", "groups.json"} {
+		var channels []slackChannel
+		err := readJSONFile(filepath.Join(dir, name), &channels)
+		if errors.Is(err, os.ErrNotExist) && name != "channels.json" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		export.channels = append(export.channels, channels...)
+	}
+	return export, nil
+}
+
+// channel finds a channel by name or ID; an empty name selects the only
+// channel of a single-channel export
+func (e *slackExport) channel(name string) (slackChannel, error) {
+	names := make([]string, len(e.channels))
+	for i, c := range e.channels {
+		names[i] = c.Name
+	}
+	sort.Strings(names)
+
+	if name == "" {
+		if len(e.channels) == 1 {
+			return e.channels[0], nil
+		}
+		return slackChannel{}, &OptionError{Option: "Channel", Value: name, Valid: names}
+	}
+
+	name = strings.TrimPrefix(name, "#")
+	for _, c := range e.channels {
+		if strings.EqualFold(c.Name, name) || c.ID == name {
+			return c, nil
+		}
+	}
+	return slackChannel{}, &OptionError{Option: "Channel", Value: name, Valid: names}
+}
+
+// entry converts a Slack message in the named channel to a chat entry
+func (e *slackExport) entry(channel string, msg slackMessage) (ChatEntry, error) {
+	ts, err := parseSlackTS(msg.TS)
+	if err != nil {
+		return ChatEntry{}, err
+	}
+
+	entry := ChatEntry{
+		Timestamp: ts,
+		User:      e.userName(msg),
+		ID:        msg.TS,
+	}
+	if msg.ThreadTS != "" && msg.ThreadTS != msg.TS {
+		entry.ParentID = msg.ThreadTS
+	}
+	if event, ok := slackEvents[msg.Subtype]; ok {
+		// The text already names the user, as in "<@U123> has joined the channel"
+		entry.Kind = KindSystem
+		entry.Message = e.text(msg.Text)
+		if entry.Message == "" {
+			entry.Message = strings.TrimSpace(entry.User + " " + event)
+		}
+		entry.User, entry.ParentID = "", ""
+		return entry, nil
+	}
+	if msg.Subtype == "tombstone" {
+		markDeleted(&entry, "")
+		return entry, nil
+	}
+
+	entry.Message, entry.Spans = markdownText(e.text(msg.Text), markdownSlack)
+	for _, f := range msg.Files {
+		name := f.Title
+		if name == "" {
+			name = f.Name
+		}
+		attachment := Attachment{
+			Name:     name,
+			MimeType: f.Mimetype,
+			Size:     f.Size,
+		}
+		if local := e.localFile(channel, f); local != "" {
+			attachment.Path = local
+		} else {
+			attachment.URL = f.URLPrivate
+		}
+		entry.Attachments = append(entry.Attachments, attachment)
+	}
+	for _, r := range msg.Reactions {
+		entry.Reactions = append(entry.Reactions, Reaction{Emoji: shortcodeEmoji(r.Name), Count: r.Count})
+	}
+	return entry, nil
+}
+
+// localFile finds a downloaded copy of a shared file: in the channel's
+// attachments folder as "ID-name", as slackdump and slack-export-viewer
+// write them, in "__uploads/ID/name", or directly in the media directory
+func (e *slackExport) localFile(channel string, f slackFile) string {
+	if f.Name == "" {
+		return ""
+	}
+	var candidates []string
+	if f.ID != "" {
+		candidates = append(candidates,
+			filepath.Join(channel, "attachments", f.ID+"-"+f.Name),
+			filepath.Join("__uploads", f.ID, f.Name),
+			f.ID+"-"+f.Name,
+		)
+	}
+	candidates = append(candidates, f.Name)
+	for _, ref := range candidates {
+		if path := localFile(e.mediaDir, ref); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// userName resolves the author of a message, falling back to the profile
+// embedded in the message and then to the bot or user name. Bot messages
+// are named by the name the bot posted under, then by the bot's app.
+func (e *slackExport) userName(msg slackMessage) string {
+	if msg.Subtype == "bot_message" {
+		switch {
+		case msg.Username != "":
+			return msg.Username
+		case msg.BotProfile != nil && msg.BotProfile.Name != "":
+			return msg.BotProfile.Name
+		}
+	}
+	if name, ok := e.users[msg.User]; ok {
+		return name
+	}
+	if msg.UserProfile != nil {
+		u := slackUser{ID: msg.User, Profile: *msg.UserProfile}
+		return u.displayName()
+	}
+	if msg.Username != "" {
+		return msg.Username
+	}
+	if msg.BotProfile != nil && msg.BotProfile.Name != "" {
+		return msg.BotProfile.Name
+	}
+	switch msg.User {
+	case "":
+		return msg.BotID
+	case "USLACKBOT":
+		return "Slackbot"
+	}
+	return msg.User
+}
+
+// slackMarkup matches the <...> references in Slack message text
+var slackMarkup = regexp.MustCompile(`<([^<>]*)>`)
+
+// slackEntities are the only HTML entities Slack escapes in message text
+var slackEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// text turns Slack markup into plain text: mentions become @name and
+// #channel, links show their label, and emoji shortcodes become emoji
+func (e *slackExport) text(s string) string {
+	s = slackMarkup.ReplaceAllStringFunc(s, func(m string) string {
+		ref := m[1 : len(m)-1]
+		target, label, hasLabel := strings.Cut(ref, "|")
+		switch {
+		case strings.HasPrefix(target, "@"):
+			if name, ok := e.users[target[1:]]; ok {
+				return "@" + name
+			}
+			if hasLabel {
+				return "@" + strings.TrimPrefix(label, "@")
+			}
+			return target
+		case strings.HasPrefix(target, "#"):
+			if hasLabel {
+				return "#" + label
+			}
+			for _, c := range e.channels {
+				if c.ID == target[1:] {
+					return "#" + c.Name
+				}
+			}
+			return target
+		case strings.HasPrefix(target, "!"):
+			// Special mentions such as <!here> or <!subteam^ID|@team>
+			if hasLabel {
+				return label
+			}
+			return "@" + target[1:]
+		case hasLabel && label != strings.TrimPrefix(target, "mailto:"):
+			return label + " (" + target + ")"
+		}
+		return strings.TrimPrefix(target, "mailto:")
+	})
+	return replaceShortcodes(slackEntities.Replace(s))
+}
+
+// parseSlackTS parses a Slack message timestamp, Unix seconds with a
+// microsecond fraction such as "1700000000.000100"
+func parseSlackTS(ts string) (time.Time, error) {
+	secs, frac, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("invalid message timestamp " + strconv.Quote(ts))
+	}
+	var micros int64
+	if frac != "" {
+		frac = (frac + "000000")[:6]
+		if micros, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, errors.New("invalid message timestamp " + strconv.Quote(ts))
+		}
+	}
+	return time.Unix(s, micros*1000), nil
+}
+
+// readJSONFile decodes the JSON file at path into v
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return pathError(path, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportSlack(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.json": `[
+  {"id": "U1", "name": "alice", "profile": {"display_name": "Alice"}},
+  {"id": "U2", "name": "bob", "real_name": "Bob Smith"}
+]`,
+		"channels.json": `[{"id": "C1", "name": "general"}, {"id": "C2", "name": "random"}]`,
+		"general/2024-03-01.json": `[
+  {"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined the channel", "ts": "1709287200.000100"},
+  {"type": "message", "user": "U1", "text": "hi <@U2|bob>, see <#C2> and <https://example.com|the site>", "ts": "1709287260.000200"},
+  {"type": "message", "user": "U2", "text": "typo", "ts": "1709287320.000300"},
+  {"type": "message", "subtype": "message_deleted", "deleted_ts": "1709287320.000300", "ts": "1709287330.000000"},
+  {"type": "message", "subtype": "bot_message", "bot_id": "B1", "bot_profile": {"name": "CI"}, "text": "build *passed*", "ts": "1709287380.000400"},
+  {"type": "message", "user": "U1", "text": "log", "ts": "1709287440.000500", "files": [{"id": "F1", "name": "log.txt", "url_private": "https://files.slack.com/F1/log.txt"}, {"id": "F2", "name": "shot.png", "url_private": "https://files.slack.com/F2/shot.png"}]}
+]`,
+		"general/2024-03-02.json": `[
+  {"type": "message", "user": "U2", "text": "reply", "ts": "1709373600.000100", "thread_ts": "1709287260.000200"}
+]`,
+		"general/attachments/F2-shot.png": "png",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if format := DetectFormat(dir); format != "slack" {
+		t.Fatalf("DetectFormat() = %q, want slack", format)
+	}
+	if _, err := Import("", dir, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "general") {
+		t.Errorf("no channel in a two-channel export: error %v does not list the channels", err)
+	}
+
+	entries, err := Import("", dir, ImportOptions{Channel: "#general"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"system  @Bob Smith has joined the channel",
+		" Alice hi @Bob Smith, see #random and the site (https://example.com)",
+		" Bob Smith This message was deleted",
+		" CI build passed",
+		" Alice log [log.txt https://files.slack.com/F1/log.txt] [shot.png " + filepath.Join(dir, "general", "attachments", "F2-shot.png") + "]",
+		" Bob Smith reply ^1709287260.000200",
+	}
+	var got []string
+	for _, e := range entries {
+		s := string(e.Kind) + " " + e.User + " " + e.Message
+		for _, a := range e.Attachments {
+			s += " [" + a.Name + " " + a.URL + a.Path + "]"
+		}
+		if e.ParentID != "" {
+			s += " ^" + e.ParentID
+		}
+		got = append(got, s)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,109 @@
+package chatpdf
+
+import "sort"
+
+// SpanStyle is a set of text styles; styles combine with |
+type SpanStyle uint
+
+// Span styles
+const (
+	StyleBold SpanStyle = 1 << iota
+	StyleItalic
+	StyleUnderline
+	StyleStrike
+	StyleCode // inline code, drawn on a shaded background
+	StyleLink // drawn in the theme's link color, and linked when the span has a URL
+
+	// StyleCodeBlock marks whole lines of code, drawn as a shaded block in
+	// the monospace font with syntax highlighting. Other styles inside it
+	// are ignored.
+	StyleCodeBlock
+)
+
+// Span styles a part of an entry's Message
+type Span struct {
+	Start, End int // byte offsets into Message, End exclusive
+	Style      SpanStyle
+	URL        string // link target for StyleLink
+	Language   string // language of a StyleCodeBlock, such as "go"; may be empty
+}
+
+// styledRuns splits text into runs at span boundaries and tokenizes each
+// part for emoji images. Overlapping spans combine their styles; where link
+// spans overlap, the one starting last gives the URL.
+func styledRuns(text string, spans []Span, images map[string]string) []textRun {
+	if len(spans) == 0 {
+		return tokenizeMessage(text, images)
+	}
+
+	// Clamp once, so spans running past the end of the text, as offsets
+	// counted in other units can, still style its tail. Spans left empty
+	// or ending before they start are dropped.
+	clamped := make([]Span, 0, len(spans))
+	cuts := []int{0, len(text)}
+	for _, s := range spans {
+		s.Start, s.End = clampOffset(s.Start, text), clampOffset(s.End, text)
+		if s.End <= s.Start {
+			continue
+		}
+		clamped = append(clamped, s)
+		cuts = append(cuts, s.Start, s.End)
+	}
+	sort.Ints(cuts)
+
+	var runs []textRun
+	for i := 0; i+1 < len(cuts); i++ {
+		start, end := cuts[i], cuts[i+1]
+		if start == end {
+			continue
+		}
+
+		var style SpanStyle
+		var url string
+		for _, s := range clamped {
+			if s.Start <= start && end <= s.End {
+				style |= s.Style
+				if s.URL != "" {
+					url = s.URL
+				}
+			}
+		}
+
+		for _, r := range tokenizeMessage(text[start:end], images) {
+			r.style, r.url = style, url
+			runs = append(runs, r)
+		}
+	}
+	return runs
+}
+
+// clampOffset limits a span offset to text and moves it back to the start
+// of a UTF-8 sequence, so bad offsets cannot split a character
+func clampOffset(offset int, text string) int {
+	if offset < 0 {
+		return 0
+	}
+	if offset > len(text) {
+		return len(text)
+	}
+	for offset > 0 && offset < len(text) && text[offset]&0xC0 == 0x80 {
+		offset--
+	}
+	return offset
+}
+
+// fontStyle returns the font style for text in the given span style on top
+// of a base font style of "", "B", "I" or "BI"
+func fontStyle(base string, style SpanStyle) string {
+	bold := style&StyleBold != 0 || base == "B" || base == "BI"
+	italic := style&StyleItalic != 0 || base == "I" || base == "BI"
+	switch {
+	case bold && italic:
+		return "BI"
+	case bold:
+		return "B"
+	case italic:
+		return "I"
+	}
+	return ""
+}
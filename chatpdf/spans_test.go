@@ -0,0 +1,56 @@
+package chatpdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStyledRuns(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		spans []Span
+		want  []string // text, style and URL of each run
+	}{
+		{
+			name:  "overlapping spans",
+			text:  "one two three",
+			spans: []Span{{Start: 0, End: 7, Style: StyleBold}, {Start: 4, End: 13, Style: StyleLink, URL: "https://a"}},
+			want:  []string{`"one " 1`, `"two" 33 https://a`, `" three" 32 https://a`},
+		},
+		{
+			name:  "spans past the end of the text",
+			text:  "short",
+			spans: []Span{{Start: 2, End: 40, Style: StyleItalic}, {Start: 10, End: 20, Style: StyleBold}},
+			want:  []string{`"sh" 0`, `"ort" 2`},
+		},
+		{
+			name:  "spans that end before they start",
+			text:  "backwards",
+			spans: []Span{{Start: 6, End: 2, Style: StyleBold}, {Start: -3, End: 4, Style: StyleCode}},
+			want:  []string{`"back" 16`, `"wards" 0`},
+		},
+		{
+			name:  "offsets inside a character",
+			text:  "a😀b",
+			spans: []Span{{Start: 2, End: 5, Style: StyleBold}, {Start: 3, End: 4, Style: StyleItalic}},
+			want:  []string{`"a" 0`, `"😀" 1`, `"b" 0`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range styledRuns(tt.text, tt.spans, nil) {
+				run := fmt.Sprintf("%q %d", r.text, r.style)
+				if r.url != "" {
+					run += " " + r.url
+				}
+				got = append(got, run)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("runs:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
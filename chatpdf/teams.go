@@ -0,0 +1,477 @@
+package chatpdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// teamsIdentity is the user or app behind a message, mention or event
+type teamsIdentity struct {
+	User *struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	Application *struct {
+		DisplayName string `json:"displayName"`
+	} `json:"application"`
+}
+
+// teamsMessage is a chat or channel message in the Microsoft Graph
+// chatMessage format, as written by Teams compliance and Graph exports
+type teamsMessage struct {
+	ID              string         `json:"id"`
+	ReplyToID       string         `json:"replyToId"`
+	ChatID          string         `json:"chatId"`
+	MessageType     string         `json:"messageType"`
+	CreatedDateTime time.Time      `json:"createdDateTime"`
+	DeletedDateTime *time.Time     `json:"deletedDateTime"`
+	Subject         string         `json:"subject"`
+	From            *teamsIdentity `json:"from"`
+	Body            struct {
+		ContentType string `json:"contentType"`
+		Content     string `json:"content"`
+	} `json:"body"`
+	ChannelIdentity *struct {
+		ChannelID string `json:"channelId"`
+	} `json:"channelIdentity"`
+	Attachments []struct {
+		ID          string `json:"id"`
+		ContentType string `json:"contentType"`
+		ContentURL  string `json:"contentUrl"`
+		Content     string `json:"content"`
+		Name        string `json:"name"`
+	} `json:"attachments"`
+	Mentions []struct {
+		MentionText string        `json:"mentionText"`
+		Mentioned   teamsIdentity `json:"mentioned"`
+	} `json:"mentions"`
+	Reactions []struct {
+		ReactionType string        `json:"reactionType"`
+		User         teamsIdentity `json:"user"`
+	} `json:"reactions"`
+	EventDetail *teamsEvent `json:"eventDetail"`
+}
+
+// teamsEvent describes a system event such as members joining a chat
+type teamsEvent struct {
+	Type      string         `json:"@odata.type"`
+	Initiator *teamsIdentity `json:"initiator"`
+	Members   []struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+	} `json:"members"`
+	ChatDisplayName    string `json:"chatDisplayName"`
+	ChannelDisplayName string `json:"channelDisplayName"`
+	TeamDisplayName    string `json:"teamDisplayName"`
+}
+
+// teamsReactions maps Teams reaction names to emoji; newer exports give
+// the emoji itself
+var teamsReactions = map[string]string{
+	"like":      "👍",
+	"heart":     "❤️",
+	"laugh":     "😆",
+	"surprised": "😮",
+	"sad":       "😢",
+	"angry":     "😠",
+}
+
+// ImportTeams reads a Microsoft Teams chat export. JSON exports hold
+// messages in the Microsoft Graph chatMessage format, as an array or in a
+// "value" or "messages" array; their HTML bodies are converted to formatted
+// text, senders that appear only by ID are named from other messages and
+// mentions, file references become attachments, replies get the parent's
+// ID as ParentID and system events become KindSystem entries. opts.Channel
+// picks a chat or channel by ID when the export holds several. HTML exports
+// are read by ImportTeamsHTML.
+func ImportTeams(path string, opts ImportOptions) ([]ChatEntry, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".html" || ext == ".htm" || strings.HasPrefix(strings.TrimLeft(readHead(path, 512), "\ufeff \t\r\n"), "<") {
+		return ImportTeamsHTML(path, opts)
+	}
+
+	messages, err := readTeamsMessages(path)
+	if err != nil {
+		return nil, err
+	}
+	messages, err = teamsConversation(messages, opts.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaDir := opts.mediaDir(path)
+	names := teamsNames(messages)
+
+	var entries []ChatEntry
+	for _, msg := range messages {
+		if !opts.inRange(msg.CreatedDateTime) {
+			continue
+		}
+		entries = append(entries, msg.entry(names, mediaDir))
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// readTeamsMessages reads a JSON array of messages or an object holding one
+// in "value" (Graph responses) or "messages"
+func readTeamsMessages(path string) ([]teamsMessage, error) {
+	var raw json.RawMessage
+	if err := readJSONFile(path, &raw); err != nil {
+		return nil, err
+	}
+	var messages []teamsMessage
+	if raw = bytes.TrimLeft(raw, " \t\r\n"); len(raw) > 0 && raw[0] == '[' {
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			return nil, pathError(path, err)
+		}
+		return messages, nil
+	}
+
+	var export struct {
+		Value    []teamsMessage `json:"value"`
+		Messages []teamsMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return nil, pathError(path, err)
+	}
+	if export.Value == nil && export.Messages == nil {
+		return nil, pathError(path, errors.New("no messages array found"))
+	}
+	return append(export.Value, export.Messages...), nil
+}
+
+// conversation returns the chat or channel ID a message belongs to
+func (msg *teamsMessage) conversation() string {
+	if msg.ChannelIdentity != nil && msg.ChannelIdentity.ChannelID != "" {
+		return msg.ChannelIdentity.ChannelID
+	}
+	return msg.ChatID
+}
+
+// teamsConversation keeps the messages of the chat or channel with the
+// given ID; an empty ID keeps all messages
+func teamsConversation(messages []teamsMessage, id string) ([]teamsMessage, error) {
+	if id == "" {
+		return messages, nil
+	}
+	seen := make(map[string]bool)
+	var kept []teamsMessage
+	for _, msg := range messages {
+		conv := msg.conversation()
+		if conv == id {
+			kept = append(kept, msg)
+		}
+		if conv != "" {
+			seen[conv] = true
+		}
+	}
+	if kept == nil {
+		valid := make([]string, 0, len(seen))
+		for conv := range seen {
+			valid = append(valid, conv)
+		}
+		sort.Strings(valid)
+		return nil, &OptionError{Option: "Channel", Value: id, Valid: valid}
+	}
+	return kept, nil
+}
+
+// teamsNames collects the display names of users by ID from senders,
+// mentions and reactions, since some exports leave the name out of the
+// sender of a message
+func teamsNames(messages []teamsMessage) map[string]string {
+	names := make(map[string]string)
+	add := func(id teamsIdentity) {
+		if id.User != nil && id.User.ID != "" && id.User.DisplayName != "" {
+			names[id.User.ID] = id.User.DisplayName
+		}
+	}
+	for _, msg := range messages {
+		if msg.From != nil {
+			add(*msg.From)
+		}
+		for _, m := range msg.Mentions {
+			add(m.Mentioned)
+		}
+		for _, r := range msg.Reactions {
+			add(r.User)
+		}
+	}
+	return names
+}
+
+// name returns the display name of a user or app
+func (id *teamsIdentity) name(names map[string]string) string {
+	switch {
+	case id == nil:
+	case id.User != nil && id.User.DisplayName != "":
+		return id.User.DisplayName
+	case id.User != nil && names[id.User.ID] != "":
+		return names[id.User.ID]
+	case id.Application != nil && id.Application.DisplayName != "":
+		return id.Application.DisplayName
+	}
+	return "Unknown user"
+}
+
+// entry converts a message to a chat entry
+func (msg *teamsMessage) entry(names map[string]string, mediaDir string) ChatEntry {
+	entry := ChatEntry{
+		Timestamp: msg.CreatedDateTime,
+		ID:        msg.ID,
+		ParentID:  msg.ReplyToID,
+	}
+	if msg.MessageType == "systemEventMessage" || msg.EventDetail != nil {
+		entry.Kind = KindSystem
+		entry.Message = msg.EventDetail.text(names)
+		return entry
+	}
+	entry.User = msg.From.name(names)
+
+	if msg.DeletedDateTime != nil {
+		markDeleted(&entry, "")
+		return entry
+	}
+
+	var text string
+	var spans []Span
+	if strings.EqualFold(msg.Body.ContentType, "html") {
+		text, spans = htmlToText(msg.Body.Content)
+	} else {
+		text = strings.TrimSpace(msg.Body.Content)
+	}
+	if msg.Subject != "" {
+		subject := msg.Subject + "\n"
+		for i := range spans {
+			spans[i].Start += len(subject)
+			spans[i].End += len(subject)
+		}
+		spans = append([]Span{{End: len(msg.Subject), Style: StyleBold}}, spans...)
+		text = subject + text
+	}
+	entry.Message, entry.Spans = strings.TrimSpace(text), spans
+
+	for _, a := range msg.Attachments {
+		switch {
+		case a.ContentType == "messageReference":
+			// A quoted reply in a chat; the body already reads as a reply
+			if entry.ParentID == "" {
+				entry.ParentID = a.ID
+			}
+		case a.ContentType == "reference" || a.ContentURL != "":
+			attachment := mediaAttachment(mediaDir, a.Name)
+			if attachment.Path == "" {
+				attachment.URL = a.ContentURL
+			}
+			entry.Attachments = append(entry.Attachments, attachment)
+		case strings.HasPrefix(a.ContentType, "application/vnd.microsoft.card."):
+			name := a.Name
+			if name == "" {
+				name = "Card"
+			}
+			entry.Attachments = append(entry.Attachments, Attachment{Name: name, MimeType: a.ContentType})
+		}
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, r := range msg.Reactions {
+		emoji := teamsReactions[r.ReactionType]
+		if emoji == "" {
+			emoji = r.ReactionType
+		}
+		if counts[emoji] == 0 {
+			order = append(order, emoji)
+		}
+		counts[emoji]++
+	}
+	for _, emoji := range order {
+		entry.Reactions = append(entry.Reactions, Reaction{Emoji: emoji, Count: counts[emoji]})
+	}
+	return entry
+}
+
+// text describes a system event such as members being added
+func (e *teamsEvent) text(names map[string]string) string {
+	if e == nil {
+		return "System event"
+	}
+	who := ""
+	if e.Initiator != nil {
+		who = e.Initiator.name(names)
+	}
+	var members []string
+	for _, m := range e.Members {
+		name := m.DisplayName
+		if name == "" {
+			name = names[m.ID]
+		}
+		if name != "" {
+			members = append(members, name)
+		}
+	}
+	if len(members) == 0 {
+		members = append(members, "a member")
+	}
+
+	kind := strings.TrimSuffix(strings.TrimPrefix(e.Type, "#microsoft.graph."), "EventMessageDetail")
+	switch kind {
+	case "membersAdded":
+		return who + " added " + strings.Join(members, ", ")
+	case "membersDeleted":
+		return who + " removed " + strings.Join(members, ", ")
+	case "membersJoined":
+		return strings.Join(members, ", ") + " joined"
+	case "membersLeft":
+		return strings.Join(members, ", ") + " left"
+	case "chatRenamed":
+		return fmt.Sprintf("%s renamed the chat to %q", who, e.ChatDisplayName)
+	case "channelRenamed":
+		return fmt.Sprintf("%s renamed the channel to %q", who, e.ChannelDisplayName)
+	case "teamRenamed":
+		return fmt.Sprintf("%s renamed the team to %q", who, e.TeamDisplayName)
+	case "callStarted":
+		return who + " started a call"
+	case "callEnded":
+		return "Call ended"
+	}
+
+	// Split the remaining camel case event names into words
+	var words strings.Builder
+	for i, r := range kind {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				words.WriteByte(' ')
+			}
+			r += 'a' - 'A'
+		}
+		words.WriteRune(r)
+	}
+	return strings.TrimSpace(who + " " + words.String())
+}
+
+// teamsTimeLayouts lists the time formats of HTML exports, tried in order
+var teamsTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	"1/2/2006, 3:04:05 PM",
+	"1/2/2006, 3:04 PM",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"Monday, January 2, 2006 3:04 PM",
+	"January 2, 2006 3:04 PM",
+}
+
+// ImportTeamsHTML reads a Teams or Outlook conversation exported as HTML.
+// Each element with the class "message" is one message; within it, the
+// elements with the classes "sender" (or "author" or "from"), "time" (or
+// "timestamp" or "date", or a <time> element) and "content" (or "body")
+// give its parts, and links with the class "attachment" or inside an
+// element with the class "attachments" become attachments. Times that do
+// not name a zone are read in opts.Location.
+func ImportTeamsHTML(path string, opts ImportOptions) ([]ChatEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	root := parseHTML(string(data))
+	blocks := root.findAll(func(n *htmlNode) bool { return n.hasClass("message") })
+	if len(blocks) == 0 {
+		return nil, pathError(path, errors.New("no elements with the class \"message\" found"))
+	}
+
+	mediaDir := opts.mediaDir(path)
+
+	var entries []ChatEntry
+	for i, block := range blocks {
+		entry, err := teamsHTMLEntry(block, mediaDir, opts.location())
+		if err != nil {
+			return nil, pathError(path, fmt.Errorf("message %d: %w", i+1, err))
+		}
+		if opts.inRange(entry.Timestamp) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// teamsHTMLEntry converts a message element to a chat entry
+func teamsHTMLEntry(block *htmlNode, mediaDir string, loc *time.Location) (ChatEntry, error) {
+	var entry ChatEntry
+	entry.ID = block.attrs["id"]
+
+	if sender := block.find(func(n *htmlNode) bool { return n.hasClass("sender", "author", "from") }); sender != nil {
+		entry.User, _ = nodeText(sender)
+		sender.remove()
+	}
+	if stamp := block.find(func(n *htmlNode) bool { return n.tag == "time" || n.hasClass("time", "timestamp", "date") }); stamp != nil {
+		value := stamp.attrs["datetime"]
+		if value == "" {
+			value, _ = nodeText(stamp)
+		}
+		ts, err := parseTeamsTime(value, loc)
+		if err != nil {
+			return entry, err
+		}
+		entry.Timestamp = ts
+		stamp.remove()
+	}
+
+	for _, link := range block.findAll(func(n *htmlNode) bool { return n.hasClass("attachment", "attachments") }) {
+		anchors := []*htmlNode{link}
+		if link.tag != "a" {
+			anchors = link.findAll(func(n *htmlNode) bool { return n.tag == "a" })
+		}
+		for _, a := range anchors {
+			name, _ := nodeText(a)
+			href := a.attrs["href"]
+			if name == "" {
+				name = filepath.Base(href)
+			}
+			attachment := mediaAttachment(mediaDir, name)
+			if local := localFile(mediaDir, href); local != "" {
+				attachment.Path = local
+			} else if attachment.Path == "" {
+				attachment.URL = href
+			}
+			entry.Attachments = append(entry.Attachments, attachment)
+		}
+		link.remove()
+	}
+
+	body := block.find(func(n *htmlNode) bool { return n.hasClass("content", "body") })
+	if body == nil {
+		body = block
+	}
+	entry.Message, entry.Spans = nodeText(body)
+	if entry.User == "" {
+		entry.Kind = KindSystem
+	}
+	return entry, nil
+}
+
+// parseTeamsTime parses the time of a message in an HTML export, in loc
+// unless it names a zone
+func parseTeamsTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, layout := range teamsTimeLayouts {
+		if ts, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
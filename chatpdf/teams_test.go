@@ -0,0 +1,67 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportTeamsHTML(t *testing.T) {
+	dir := t.TempDir()
+	page := `<html><head><title>Chat</title></head><body>
+<div class="message" id="m1">
+  <span class="sender">Alice</span> <span class="time">2024-03-01 10:00</span>
+  <div class="content"><p>Hello <b>team </b></p><p>see <a href="https://example.com">this</a></p></div>
+</div>
+<div class="message" id="m2">
+  <span class="author">Bob</span> <time datetime="2024-03-01T09:05:00Z">Friday</time>
+  <div class="body">notes attached</div>
+  <div class="attachments"><a href="files/notes.txt">notes.txt</a> <a href="https://example.com/x.pdf">x.pdf</a></div>
+</div>
+<div class="message"><span class="date">3/1/2024 10:10 AM</span><div class="content">Bob left the chat</div></div>
+</body></html>`
+	path := filepath.Join(dir, "chat.html")
+	if err := os.WriteFile(path, []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "files", "notes.txt"), []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ImportTeamsHTML(path, ImportOptions{Location: time.FixedZone("UTC+1", 3600)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"09:00  Alice m1 \"Hello team\\nsee this\" \"team\" 1, \"this\" 32 https://example.com",
+		"09:05  Bob m2 \"notes attached\" [notes.txt " + filepath.Join(dir, "files", "notes.txt") + "] [x.pdf https://example.com/x.pdf]",
+		"09:10 system   \"Bob left the chat\"",
+	}
+	var got []string
+	for _, e := range entries {
+		s := e.Timestamp.UTC().Format("15:04") + " " + string(e.Kind) + " " + e.User + " " + e.ID + " " + strconv.Quote(e.Message)
+		if len(e.Spans) > 0 {
+			s += " " + spanList(e.Message, e.Spans)
+		}
+		for _, a := range e.Attachments {
+			s += " [" + a.Name + " " + a.URL + a.Path + "]"
+		}
+		got = append(got, s)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if err := os.WriteFile(path, []byte("<p>no messages</p>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportTeamsHTML(path, ImportOptions{}); err == nil || !strings.Contains(err.Error(), `class "message"`) {
+		t.Errorf("no message elements: error = %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package chatpdf
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramChat is one chat of a Telegram Desktop export
+type telegramChat struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	ID       json.Number       `json:"id"`
+	Messages []telegramMessage `json:"messages"`
+}
+
+// telegramExport is result.json, holding either a single chat or, for a
+// full account export, a list of chats
+type telegramExport struct {
+	telegramChat
+	Chats struct {
+		List []telegramChat `json:"list"`
+	} `json:"chats"`
+}
+
+// telegramEntity is a piece of message text with its formatting
+type telegramEntity struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	Href string `json:"href"`
+
+	Language string `json:"language"` // of a "pre" code block
+}
+
+// telegramText is message text, which the export writes either as a plain
+// string or as an array mixing strings and entities
+type telegramText []telegramEntity
+
+func (t *telegramText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = telegramText{{Type: "plain", Text: s}}
+		return nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("text must be a string or an array")
+	}
+	*t = nil
+	for _, part := range parts {
+		var e telegramEntity
+		if err := json.Unmarshal(part, &e.Text); err == nil {
+			e.Type = "plain"
+		} else if err := json.Unmarshal(part, &e); err != nil {
+			return err
+		}
+		*t = append(*t, e)
+	}
+	return nil
+}
+
+// telegramMessage is a message or service event of a chat
+type telegramMessage struct {
+	ID               json.Number      `json:"id"`
+	Type             string           `json:"type"`
+	Date             string           `json:"date"`
+	DateUnixtime     string           `json:"date_unixtime"`
+	From             *string          `json:"from"`
+	Actor            string           `json:"actor"`
+	Action           string           `json:"action"`
+	Title            string           `json:"title"`
+	Members          []*string        `json:"members"`
+	Text             telegramText     `json:"text"`
+	TextEntities     []telegramEntity `json:"text_entities"`
+	ReplyToMessageID json.Number      `json:"reply_to_message_id"`
+	ForwardedFrom    *string          `json:"forwarded_from"`
+	Photo            string           `json:"photo"`
+	File             string           `json:"file"`
+	FileName         string           `json:"file_name"`
+	MediaType        string           `json:"media_type"`
+	MimeType         string           `json:"mime_type"`
+	StickerEmoji     string           `json:"sticker_emoji"`
+	Reactions        []struct {
+		Type  string `json:"type"`
+		Count int    `json:"count"`
+		Emoji string `json:"emoji"`
+	} `json:"reactions"`
+}
+
+// telegramStyles maps entity types to span styles
+var telegramStyles = map[string]SpanStyle{
+	"bold":          StyleBold,
+	"italic":        StyleItalic,
+	"underline":     StyleUnderline,
+	"strikethrough": StyleStrike,
+	"code":          StyleCode,
+	"pre":           StyleCodeBlock,
+	"link":          StyleLink,
+	"text_link":     StyleLink,
+	"email":         StyleLink,
+	"phone":         StyleLink,
+	"mention":       StyleLink,
+	"mention_name":  StyleLink,
+	"hashtag":       StyleLink,
+	"cashtag":       StyleLink,
+	"bot_command":   StyleLink,
+	"blockquote":    StyleItalic,
+}
+
+// telegramNotIncluded starts the file fields of media that was not exported
+const telegramNotIncluded = "(File not included"
+
+// ImportTelegram reads a chat from Telegram Desktop's JSON export
+// (result.json). Text entities such as bold, code and links become
+// formatting spans, replies get the replied-to message's ID as ParentID,
+// forwarded messages are marked with their origin, and service events such
+// as members joining become KindSystem entries. For a full account export,
+// opts.Channel picks the chat by name or ID. Exports without Unix times
+// are read in opts.Location.
+func ImportTelegram(path string, opts ImportOptions) ([]ChatEntry, error) {
+	var export telegramExport
+	if err := readJSONFile(path, &export); err != nil {
+		return nil, err
+	}
+	chat, err := export.chat(opts.Channel)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaDir := opts.mediaDir(path)
+
+	var entries []ChatEntry
+	for _, msg := range chat.Messages {
+		entry, err := msg.entry(mediaDir, opts.location())
+		if err != nil {
+			return nil, pathError(path, err)
+		}
+		if opts.inRange(entry.Timestamp) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sortEntries(entries)
+	return entries, nil
+}
+
+// chat finds a chat by name or ID; an empty name selects the export's only chat
+func (e *telegramExport) chat(name string) (*telegramChat, error) {
+	if e.Chats.List == nil {
+		if name != "" && !strings.EqualFold(name, e.Name) && name != e.ID.String() {
+			return nil, &OptionError{Option: "Channel", Value: name, Valid: []string{e.Name}}
+		}
+		return &e.telegramChat, nil
+	}
+
+	names := make([]string, len(e.Chats.List))
+	for i, c := range e.Chats.List {
+		names[i] = c.Name
+	}
+	sort.Strings(names)
+
+	if name == "" && len(e.Chats.List) == 1 {
+		return &e.Chats.List[0], nil
+	}
+	for i, c := range e.Chats.List {
+		if name != "" && (strings.EqualFold(c.Name, name) || c.ID.String() == name) {
+			return &e.Chats.List[i], nil
+		}
+	}
+	return nil, &OptionError{Option: "Channel", Value: name, Valid: names}
+}
+
+// time returns the time of the message, preferring the Unix time written by
+// newer exports over the date without a zone, which is read in loc
+func (msg *telegramMessage) time(loc *time.Location) (time.Time, error) {
+	if msg.DateUnixtime != "" {
+		secs, err := strconv.ParseInt(msg.DateUnixtime, 10, 64)
+		if err == nil {
+			return time.Unix(secs, 0), nil
+		}
+	}
+	ts, err := time.ParseInLocation("2006-01-02T15:04:05", msg.Date, loc)
+	if err != nil {
+		return time.Time{}, errors.New("message " + msg.ID.String() + ": invalid date " + strconv.Quote(msg.Date))
+	}
+	return ts, nil
+}
+
+// entry converts a message or service event to a chat entry
+func (msg *telegramMessage) entry(mediaDir string, loc *time.Location) (ChatEntry, error) {
+	ts, err := msg.time(loc)
+	if err != nil {
+		return ChatEntry{}, err
+	}
+
+	entry := ChatEntry{
+		Timestamp: ts,
+		ID:        msg.ID.String(),
+		ParentID:  msg.ReplyToMessageID.String(),
+	}
+	if msg.Type == "service" {
+		entry.Kind = KindSystem
+		entry.Message = msg.serviceText()
+		return entry, nil
+	}
+
+	entry.User = "Deleted Account"
+	if msg.From != nil {
+		entry.User = *msg.From
+	}
+
+	var text strings.Builder
+	if msg.ForwardedFrom != nil {
+		text.WriteString("Forwarded from " + *msg.ForwardedFrom)
+		entry.Spans = append(entry.Spans, Span{End: text.Len(), Style: StyleItalic})
+		text.WriteString("\n")
+	}
+	entities := msg.TextEntities
+	if entities == nil {
+		entities = msg.Text
+	}
+	for _, e := range entities {
+		start := text.Len()
+		text.WriteString(e.Text)
+		style, ok := telegramStyles[e.Type]
+		if !ok || e.Text == "" {
+			continue
+		}
+		span := Span{Start: start, End: text.Len(), Style: style}
+		switch e.Type {
+		case "text_link":
+			span.URL = e.Href
+		case "link":
+			span.URL = e.Text
+			if !strings.Contains(e.Text, "://") {
+				span.URL = "https://" + e.Text
+			}
+		case "email":
+			span.URL = "mailto:" + e.Text
+		case "pre":
+			span.Language = e.Language
+		}
+		entry.Spans = append(entry.Spans, span)
+	}
+	entry.Message = text.String()
+
+	for _, media := range []string{msg.Photo, msg.File} {
+		if media == "" {
+			continue
+		}
+		if strings.HasPrefix(media, telegramNotIncluded) {
+			name := msg.FileName
+			if name == "" {
+				name = strings.ReplaceAll(msg.MediaType, "_", " ")
+			}
+			if name == "" {
+				name = "photo"
+			}
+			entry.Attachments = append(entry.Attachments, Attachment{Name: name + " (not included in export)", MimeType: msg.MimeType})
+			continue
+		}
+		a := mediaAttachment(mediaDir, filepath.FromSlash(media))
+		a.Name = filepath.Base(a.Name)
+		if msg.MediaType == "sticker" && msg.StickerEmoji != "" {
+			a.Name = "Sticker " + msg.StickerEmoji
+		}
+		entry.Attachments = append(entry.Attachments, a)
+	}
+
+	for _, r := range msg.Reactions {
+		emoji := r.Emoji
+		if emoji == "" {
+			emoji = "custom emoji"
+		}
+		entry.Reactions = append(entry.Reactions, Reaction{Emoji: emoji, Count: r.Count})
+	}
+	return entry, nil
+}
+
+// serviceText describes a service event such as a member joining
+func (msg *telegramMessage) serviceText() string {
+	var members []string
+	for _, m := range msg.Members {
+		if m != nil {
+			members = append(members, *m)
+		}
+	}
+	who := strings.Join(members, ", ")
+
+	switch msg.Action {
+	case "create_group", "create_channel":
+		return msg.Actor + " created " + strconv.Quote(msg.Title)
+	case "edit_group_title":
+		return msg.Actor + " changed the title to " + strconv.Quote(msg.Title)
+	case "invite_members":
+		return msg.Actor + " added " + who
+	case "remove_members":
+		return msg.Actor + " removed " + who
+	case "join_group_by_link":
+		return msg.Actor + " joined the group via invite link"
+	case "pin_message":
+		return msg.Actor + " pinned a message"
+	case "edit_group_photo":
+		return msg.Actor + " changed the group photo"
+	case "phone_call":
+		return msg.Actor + " called"
+	}
+	return strings.TrimSpace(msg.Actor + " " + strings.ReplaceAll(msg.Action, "_", " "))
+}
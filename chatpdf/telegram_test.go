@@ -0,0 +1,68 @@
+package chatpdf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportTelegram(t *testing.T) {
+	export := `{
+  "name": "Friends",
+  "type": "private_group",
+  "id": 7,
+  "messages": [
+    {"id": 1, "type": "service", "date": "2024-03-01T10:00:00", "actor": "Alice", "action": "invite_members", "members": ["Bob", null]},
+    {"id": 2, "type": "message", "date": "2024-03-01T10:01:00", "from": "Alice",
+     "text": "ignored when text_entities is present",
+     "text_entities": [
+       {"type": "plain", "text": "😀👍🏽 "},
+       {"type": "bold", "text": "bold 𝄞"},
+       {"type": "plain", "text": " see "},
+       {"type": "link", "text": "go.dev"},
+       {"type": "plain", "text": " and "},
+       {"type": "text_link", "text": "docs 📄", "href": "https://example.com/docs"},
+       {"type": "custom_emoji", "text": "🔥"},
+       {"type": "pre", "text": "x := 1", "language": "go"}
+     ]},
+    {"id": 3, "type": "message", "date": "2024-03-01T10:02:00", "date_unixtime": "1709287320", "from": "Bob", "reply_to_message_id": 2,
+     "text": ["plain ", {"type": "italic", "text": "𝑖"}], "forwarded_from": "Carol"}
+  ]
+}`
+	path := filepath.Join(t.TempDir(), "result.json")
+	if err := os.WriteFile(path, []byte(export), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ImportTelegram(path, ImportOptions{Location: time.FixedZone("UTC+2", 2*3600)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"08:00 system  Alice added Bob",
+		`08:01  Alice "😀👍🏽 bold 𝄞 see go.dev and docs 📄🔥x := 1"` + "\n" +
+			`"bold 𝄞" 1, "go.dev" 32 https://go.dev, "docs 📄" 32 https://example.com/docs, "x := 1" 64 go`,
+		`10:02  Bob "Forwarded from Carol\nplain 𝑖" ^2` + "\n" + `"Forwarded from Carol" 2, "𝑖" 2`,
+	}
+	var got []string
+	for _, e := range entries {
+		s := e.Timestamp.UTC().Format("15:04") + " " + string(e.Kind) + " " + e.User + " "
+		if e.Kind == KindSystem {
+			s += e.Message
+		} else {
+			s += fmt.Sprintf("%q", e.Message)
+		}
+		if e.ParentID != "" {
+			s += " ^" + e.ParentID
+		}
+		if len(e.Spans) > 0 {
+			s += "\n" + spanList(e.Message, e.Spans)
+		}
+		got = append(got, s)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,126 @@
+package chatpdf
+
+import (
+	"sort"
+	"strings"
+)
+
+// Color is an RGB color with components in the range 0-255
+type Color struct {
+	R, G, B int
+}
+
+// Theme holds the colors used to draw a document
+type Theme struct {
+	Background Color // page background; white pages are left unpainted
+	Title      Color // header title
+	Meta       Color // timestamps and user names
+	Text       Color // message text
+	Footer     Color // footer text
+	Link       Color // footer link
+	Rule       Color // header and footer separator lines
+}
+
+// themes lists the built-in themes by name
+var themes = map[string]Theme{
+	"light": {
+		Background: Color{255, 255, 255},
+		Title:      Color{0, 0, 0},
+		Meta:       Color{100, 100, 100},
+		Text:       Color{0, 0, 0},
+		Footer:     Color{128, 128, 128},
+		Link:       Color{0, 0, 255},
+		Rule:       Color{200, 200, 200},
+	},
+	"dark": {
+		Background: Color{30, 30, 30},
+		Title:      Color{240, 240, 240},
+		Meta:       Color{160, 160, 160},
+		Text:       Color{230, 230, 230},
+		Footer:     Color{140, 140, 140},
+		Link:       Color{110, 170, 255},
+		Rule:       Color{80, 80, 80},
+	},
+}
+
+// ThemeNames returns the names of the built-in themes in sorted order
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTheme resolves a theme name, defaulting to "light"
+func lookupTheme(name string) (Theme, error) {
+	if name == "" {
+		name = "light"
+	}
+	theme, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return Theme{}, &OptionError{Option: "Theme", Value: name, Valid: ThemeNames()}
+	}
+	return theme, nil
+}
+
+// ColorMode selects where an entry's R/G/B color is applied
+type ColorMode string
+
+// Supported color modes
+const (
+	ColorText   ColorMode = "text"   // message text is drawn in the entry color
+	ColorBar    ColorMode = "bar"    // a bar in the entry color runs down the left of the message
+	ColorBubble ColorMode = "bubble" // the message sits on a tinted background
+)
+
+// colorModes lists the supported color modes
+var colorModes = []ColorMode{ColorText, ColorBar, ColorBubble}
+
+// ColorModes returns the names of the supported color modes
+func ColorModes() []string {
+	names := make([]string, len(colorModes))
+	for i, mode := range colorModes {
+		names[i] = string(mode)
+	}
+	return names
+}
+
+// lookupColorMode validates a color mode, defaulting to ColorText
+func lookupColorMode(mode ColorMode) (ColorMode, error) {
+	if mode == "" {
+		return ColorText, nil
+	}
+	for _, m := range colorModes {
+		if strings.EqualFold(string(m), string(mode)) {
+			return m, nil
+		}
+	}
+	return "", &OptionError{Option: "ColorMode", Value: string(mode), Valid: ColorModes()}
+}
+
+// entryColor returns the entry's color and whether one was set; black is
+// treated as unset because it is the zero value
+func entryColor(entry ChatEntry) (Color, bool) {
+	c := Color{entry.R, entry.G, entry.B}
+	return c, c != Color{}
+}
+
+// mix blends c toward other; amount 0 returns c and 1 returns other
+func (c Color) mix(other Color, amount float64) Color {
+	blend := func(a, b int) int {
+		return int(float64(a) + (float64(b)-float64(a))*amount + 0.5)
+	}
+	return Color{blend(c.R, other.R), blend(c.G, other.G), blend(c.B, other.B)}
+}
+
+// isWhite reports whether c is pure white
+func (c Color) isWhite() bool {
+	return c.R == 255 && c.G == 255 && c.B == 255
+}
+
+// isDark reports whether c is closer to black than to white
+func (c Color) isDark() bool {
+	return 299*c.R+587*c.G+114*c.B < 128*1000
+}
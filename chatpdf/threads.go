@@ -0,0 +1,168 @@
+package chatpdf
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ThreadMode selects how replies are placed relative to the message they
+// answer
+type ThreadMode string
+
+// Supported thread modes
+const (
+	ThreadFlat   ThreadMode = "flat"   // entries stay in order and replies quote their parent
+	ThreadNested ThreadMode = "nested" // replies follow their parent, indented one step per level
+)
+
+// threadModes lists the supported thread modes
+var threadModes = []ThreadMode{ThreadFlat, ThreadNested}
+
+// ThreadModes returns the names of the supported thread modes
+func ThreadModes() []string {
+	names := make([]string, len(threadModes))
+	for i, m := range threadModes {
+		names[i] = string(m)
+	}
+	return names
+}
+
+// lookupThreadMode validates a thread mode, defaulting to ThreadFlat
+func lookupThreadMode(m ThreadMode) (ThreadMode, error) {
+	if m == "" {
+		return ThreadFlat, nil
+	}
+	for _, known := range threadModes {
+		if strings.EqualFold(string(known), string(m)) {
+			return known, nil
+		}
+	}
+	return "", &OptionError{Option: "Threads", Value: string(m), Valid: ThreadModes()}
+}
+
+// Thread geometry in mm
+const (
+	threadIndent   = 8.0 // indent per reply level in nested mode
+	maxThreadDepth = 3   // deeper replies are indented as this level
+)
+
+// quoteHeight is the height of a one line quote, with the gap below it
+const quoteHeight = 9*ptToMM*lineSpacing + 1.0
+
+// quoteLength is the number of characters of the parent message quoted
+// above a reply
+const quoteLength = 140
+
+// threadItem is an entry in the order it is drawn, with its place in a thread
+type threadItem struct {
+	entry  ChatEntry
+	parent *ChatEntry // the message quoted above the entry, if any
+	depth  int        // reply level, 0 for messages that start a thread
+}
+
+// threadItems arranges the entries according to the thread mode. Replies
+// whose parent is not among the entries, for example because it was
+// filtered out by date, are drawn as ordinary messages.
+func (g *PDFGenerator) threadItems() []threadItem {
+	byID := make(map[string]int, len(g.entries))
+	for i, entry := range g.entries {
+		if entry.ID == "" {
+			continue
+		}
+		if _, seen := byID[entry.ID]; !seen {
+			byID[entry.ID] = i
+		}
+	}
+	parentOf := func(i int) int {
+		entry := g.entries[i]
+		if entry.ParentID == "" || entry.ParentID == entry.ID {
+			return -1
+		}
+		if p, ok := byID[entry.ParentID]; ok && p != i {
+			return p
+		}
+		return -1
+	}
+
+	items := make([]threadItem, 0, len(g.entries))
+	if g.threads == ThreadFlat {
+		for i, entry := range g.entries {
+			item := threadItem{entry: entry}
+			if p := parentOf(i); p >= 0 {
+				item.parent = &g.entries[p]
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+
+	// Nested: each message is followed by its replies, in order
+	replies := make(map[int][]int)
+	var roots []int
+	for i := range g.entries {
+		if p := parentOf(i); p >= 0 {
+			replies[p] = append(replies[p], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+	placed := make([]bool, len(g.entries))
+	var place func(i, depth int)
+	place = func(i, depth int) {
+		if placed[i] {
+			return
+		}
+		placed[i] = true
+		items = append(items, threadItem{entry: g.entries[i], depth: min(depth, maxThreadDepth)})
+		for _, r := range replies[i] {
+			place(r, depth+1)
+		}
+	}
+	for _, i := range roots {
+		place(i, 0)
+	}
+	// Replies in a cycle have no root; keep them in their original order
+	for i := range g.entries {
+		place(i, 0)
+	}
+	return items
+}
+
+// addQuote draws a one or two line quote of the parent message above a
+// reply, with a bar down its left side
+func (g *PDFGenerator) addQuote(parent *ChatEntry, x, width float64) {
+	const fontSize = 9
+	const barWidth, barGap, quoteGap = 0.8, 2.0, 1.0
+
+	text := quoteText(*parent)
+	var spans []Span
+	if parent.User != "" {
+		prefix := parent.User + ": "
+		spans = []Span{{End: len(parent.User), Style: StyleBold}}
+		text = prefix + text
+	}
+
+	g.setFont("", fontSize)
+	g.setTextColor(g.theme.Meta)
+	runs := styledRuns(text, spans, g.entryEmoji(*parent))
+	lines := g.wrapRuns(runs, width-barWidth-barGap, fontSize)
+	g.drawLines(lines, x+barWidth+barGap, fontSize, 0, func(top, height float64) {
+		g.backend.SetFillColor(g.theme.Rule)
+		g.backend.Rect(x, top, barWidth, height, "F")
+	})
+	g.y += quoteGap
+}
+
+// quoteText returns the start of a message on a single line, shortened to
+// quoteLength characters
+func quoteText(entry ChatEntry) string {
+	text := strings.Join(strings.Fields(entry.Message), " ")
+	if text == "" && len(entry.Attachments) > 0 {
+		text = "Attachment: " + entry.Attachments[0].Name
+	}
+	if utf8.RuneCountInString(text) > quoteLength {
+		runes := []rune(text)
+		text = strings.TrimSpace(string(runes[:quoteLength])) + "…"
+	}
+	return text
+}
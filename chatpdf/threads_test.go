@@ -0,0 +1,86 @@
+package chatpdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestThreadItems(t *testing.T) {
+	// entries are written as "id" or "id>parent"
+	tests := []struct {
+		name    string
+		threads ThreadMode
+		entries []string
+		want    []string // id, depth and quoted parent of each item
+	}{
+		{
+			name:    "flat keeps order and quotes parents",
+			threads: ThreadFlat,
+			entries: []string{"1", "2", "3>1", "4>3"},
+			want:    []string{"1 0", "2 0", "3 0 ^1", "4 0 ^3"},
+		},
+		{
+			name:    "nested replies follow their parent",
+			threads: ThreadNested,
+			entries: []string{"1", "2", "3>1", "4>2", "5>3", "6>1"},
+			want:    []string{"1 0", "3 1", "5 2", "6 1", "2 0", "4 1"},
+		},
+		{
+			name:    "nested depth is clamped",
+			threads: ThreadNested,
+			entries: []string{"1", "2>1", "3>2", "4>3", "5>4", "6>5"},
+			want:    []string{"1 0", "2 1", "3 2", "4 3", "5 3", "6 3"},
+		},
+		{
+			name:    "flat self parent",
+			threads: ThreadFlat,
+			entries: []string{"1>1", "2>1"},
+			want:    []string{"1 0", "2 0 ^1"},
+		},
+		{
+			name:    "nested self parent",
+			threads: ThreadNested,
+			entries: []string{"1>1", "2>1"},
+			want:    []string{"1 0", "2 1"},
+		},
+		{
+			name:    "nested two entry cycle keeps its order",
+			threads: ThreadNested,
+			entries: []string{"1", "2>3", "3>2", "4>1"},
+			want:    []string{"1 0", "4 1", "2 0", "3 1"},
+		},
+		{
+			name:    "flat missing parent",
+			threads: ThreadFlat,
+			entries: []string{"1", "2>9"},
+			want:    []string{"1 0", "2 0"},
+		},
+		{
+			name:    "nested missing parent",
+			threads: ThreadNested,
+			entries: []string{"2>9", "1", "3>2"},
+			want:    []string{"2 0", "3 1", "1 0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &PDFGenerator{threads: tt.threads}
+			for _, e := range tt.entries {
+				id, parent, _ := strings.Cut(e, ">")
+				g.entries = append(g.entries, ChatEntry{ID: id, ParentID: parent, User: "u", Message: "m" + id})
+			}
+			var got []string
+			for _, item := range g.threadItems() {
+				s := fmt.Sprintf("%s %d", item.entry.ID, item.depth)
+				if item.parent != nil {
+					s += " ^" + item.parent.ID
+				}
+				got = append(got, s)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("items:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
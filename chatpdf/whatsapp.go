@@ -0,0 +1,287 @@
+package chatpdf
+
+import (
+	"bufio"
+	"errors"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// whatsappLine matches the first line of a message in a WhatsApp "Export
+// chat" file, in both the Android form
+//
+//	31/12/2020, 21:41 - Alice: Happy new year
+//
+// and the iOS form
+//
+//	[31/12/2020, 21:41:05] Alice: Happy new year
+//
+// The date separator and order, the 12 or 24 hour clock and the seconds
+// depend on the phone's locale.
+var whatsappLine = regexp.MustCompile(`^[\x{200E}\x{200F}]?\[?(\d{1,4})[./-](\d{1,2})[./-](\d{1,4}),? (\d{1,2})[:.](\d{2})(?:[:.](\d{2}))?(?:[ \x{202F}\x{00A0}]?([AaPp])\.? ?[Mm]\.?)?\]?(?: -|:)? (.*)$`)
+
+// whatsappMarks are the direction marks WhatsApp puts around names and notices
+var whatsappMarks = strings.NewReplacer("\u200e", "", "\u200f", "")
+
+// whatsappOmitted matches the iOS placeholders for media left out of an export
+var whatsappOmitted = regexp.MustCompile(`^(image|video|audio|sticker|GIF|document|Contact card) omitted$`)
+
+// whatsappAttached matches a media file included in an export
+var whatsappAttached = regexp.MustCompile(`^<attached: (.+)>$|^(.+\.\w+) \(file attached\)$`)
+
+// whatsappMessage is a message header with its text, before the date order
+// is known
+type whatsappMessage struct {
+	date    [3]int // the three date fields in file order
+	hour    int
+	minute  int
+	second  int
+	ampm    byte // 'a', 'p' or 0 for a 24 hour clock
+	rest    string
+	ios     bool
+	lineNo  int
+	content []string
+}
+
+// ImportWhatsApp reads a chat exported with WhatsApp's "Export chat". The
+// date format is detected from the file. Lines without a date continue the
+// message before them, notices such as members joining become KindSystem
+// entries, and media files are looked up in opts.MediaDir, which defaults to
+// the directory of the export. Times are taken in opts.Location.
+func ImportWhatsApp(path string, opts ImportOptions) ([]ChatEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var messages []*whatsappMessage
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if lineNo == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		msg := parseWhatsAppLine(text)
+		if msg == nil {
+			// Lines without a date continue the previous message
+			if len(messages) > 0 {
+				last := messages[len(messages)-1]
+				last.content = append(last.content, text)
+			}
+			continue
+		}
+		msg.lineNo = lineNo
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, pathError(path, err)
+	}
+	if len(messages) == 0 {
+		return nil, pathError(path, errors.New("no WhatsApp messages found"))
+	}
+
+	mediaDir := opts.mediaDir(path)
+	loc := opts.location()
+	order := whatsappDateOrder(messages, loc)
+
+	var entries []ChatEntry
+	for _, msg := range messages {
+		ts, err := msg.time(order, loc)
+		if err != nil {
+			return nil, pathError(path, &LineError{Line: msg.lineNo, Err: err})
+		}
+		if !opts.inRange(ts) {
+			continue
+		}
+		entries = append(entries, msg.entry(ts, mediaDir))
+	}
+	return entries, nil
+}
+
+// parseWhatsAppLine parses the first line of a message, or returns nil for
+// a continuation line
+func parseWhatsAppLine(text string) *whatsappMessage {
+	m := whatsappLine.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+
+	msg := &whatsappMessage{
+		rest: m[8],
+		ios:  strings.HasPrefix(strings.TrimLeft(text, "\u200e\u200f"), "["),
+	}
+	for i := range msg.date {
+		msg.date[i], _ = strconv.Atoi(m[i+1])
+	}
+	msg.hour, _ = strconv.Atoi(m[4])
+	msg.minute, _ = strconv.Atoi(m[5])
+	msg.second, _ = strconv.Atoi(m[6])
+	if m[7] != "" {
+		msg.ampm = strings.ToLower(m[7])[0]
+	}
+	return msg
+}
+
+// Date field orders of a WhatsApp export
+const (
+	orderDMY = iota
+	orderMDY
+	orderYMD
+)
+
+// whatsappDateOrder works out the order of the date fields. A four digit
+// first field means year first; a field above 12 rules out it being the
+// month. When both day and month first are still possible, the order that
+// keeps the messages in time order wins, with ties going to month first for
+// 12 hour clocks (the US format) and day first otherwise.
+func whatsappDateOrder(messages []*whatsappMessage, loc *time.Location) int {
+	dayFirst, monthFirst, twelveHour := true, true, false
+	for _, msg := range messages {
+		if msg.date[0] > 31 {
+			return orderYMD
+		}
+		if msg.date[0] > 12 {
+			monthFirst = false
+		}
+		if msg.date[1] > 12 {
+			dayFirst = false
+		}
+		if msg.ampm != 0 {
+			twelveHour = true
+		}
+	}
+	switch {
+	case dayFirst && !monthFirst:
+		return orderDMY
+	case monthFirst && !dayFirst:
+		return orderMDY
+	}
+
+	dmy, mdy := outOfOrder(messages, orderDMY, loc), outOfOrder(messages, orderMDY, loc)
+	switch {
+	case mdy < dmy:
+		return orderMDY
+	case dmy < mdy:
+		return orderDMY
+	case twelveHour:
+		return orderMDY
+	}
+	return orderDMY
+}
+
+// outOfOrder counts the messages dated before the message preceding them
+// when the dates are read in the given order
+func outOfOrder(messages []*whatsappMessage, order int, loc *time.Location) int {
+	var count int
+	var prev time.Time
+	for _, msg := range messages {
+		ts, err := msg.time(order, loc)
+		if err != nil {
+			count++
+			continue
+		}
+		if ts.Before(prev) {
+			count++
+		}
+		prev = ts
+	}
+	return count
+}
+
+// time returns the message time in loc, reading the date fields in the
+// given order
+func (msg *whatsappMessage) time(order int, loc *time.Location) (time.Time, error) {
+	var year, month, day int
+	switch order {
+	case orderDMY:
+		day, month, year = msg.date[0], msg.date[1], msg.date[2]
+	case orderMDY:
+		month, day, year = msg.date[0], msg.date[1], msg.date[2]
+	default:
+		year, month, day = msg.date[0], msg.date[1], msg.date[2]
+	}
+	if year < 100 {
+		year += 2000
+	}
+
+	hour := msg.hour
+	switch msg.ampm {
+	case 'a':
+		if hour == 12 {
+			hour = 0
+		}
+	case 'p':
+		if hour < 12 {
+			hour += 12
+		}
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || msg.minute > 59 || msg.second > 59 {
+		return time.Time{}, errors.New("invalid date")
+	}
+	ts := time.Date(year, time.Month(month), day, hour, msg.minute, msg.second, 0, loc)
+	if ts.Day() != day {
+		return time.Time{}, errors.New("invalid date")
+	}
+	return ts, nil
+}
+
+// entry converts the message to a chat entry
+func (msg *whatsappMessage) entry(ts time.Time, mediaDir string) ChatEntry {
+	entry := ChatEntry{Timestamp: ts}
+
+	// Notices have no sender. iOS exports give them the chat's name as
+	// sender and mark the text with a direction mark.
+	user, text, ok := strings.Cut(msg.rest, ": ")
+	if !ok || strings.ContainsAny(user, "\"\u201c\u201d") {
+		entry.Kind = KindSystem
+		entry.Message = whatsappMarks.Replace(strings.Join(append([]string{msg.rest}, msg.content...), "\n"))
+		return entry
+	}
+	entry.User = whatsappMarks.Replace(user)
+
+	first := strings.TrimSpace(whatsappMarks.Replace(text))
+	switch {
+	case first == "<Media omitted>":
+		entry.Attachments = []Attachment{{Name: "Media omitted"}}
+		first = ""
+	case whatsappOmitted.MatchString(first):
+		entry.Attachments = []Attachment{{Name: strings.ToUpper(first[:1]) + first[1:]}}
+		first = ""
+	case whatsappAttached.MatchString(first):
+		m := whatsappAttached.FindStringSubmatch(first)
+		name := m[1] + m[2]
+		entry.Attachments = []Attachment{mediaAttachment(mediaDir, name)}
+		first = ""
+	case msg.ios && strings.HasPrefix(text, "\u200e"):
+		entry.Kind = KindSystem
+		entry.User = ""
+	}
+
+	lines := append([]string{first}, msg.content...)
+	if first == "" {
+		lines = lines[1:]
+	}
+	entry.Message = whatsappMarks.Replace(strings.Join(lines, "\n"))
+	return entry
+}
+
+// mediaAttachment describes a media file of an export, with its path and
+// size when the file is present in mediaDir
+func mediaAttachment(mediaDir, name string) Attachment {
+	a := Attachment{Name: name, MimeType: mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))}
+	if path := localFile(mediaDir, name); path != "" {
+		a.Path = path
+		if info, err := os.Stat(path); err == nil {
+			a.Size = info.Size()
+		}
+	}
+	return a
+}
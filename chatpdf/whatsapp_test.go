@@ -0,0 +1,142 @@
+package chatpdf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportWhatsAppDateFormats(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		opts  ImportOptions // Location defaults to UTC
+		want  []string      // entry times in UTC as 2006-01-02 15:04:05
+	}{
+		{
+			name: "day first from a day above 12",
+			lines: []string{
+				"13/03/2024, 09:05 - Alice: hi",
+				"14/03/2024, 21:30 - Bob: hello",
+			},
+			want: []string{"2024-03-13 09:05:00", "2024-03-14 21:30:00"},
+		},
+		{
+			name: "month first from a day above 12",
+			lines: []string{
+				"3/13/24, 9:05 AM - Alice: hi",
+				"3/14/24, 9:30 PM - Bob: hello",
+			},
+			want: []string{"2024-03-13 09:05:00", "2024-03-14 21:30:00"},
+		},
+		{
+			name: "ambiguous dates ordered by time",
+			lines: []string{
+				"01/02/2024, 10:00 - Alice: hi",
+				"05/02/2024, 10:00 - Bob: hello",
+				"01/03/2024, 10:00 - Alice: later",
+			},
+			// Read month first, 5 January would come before 2 January
+			want: []string{"2024-02-01 10:00:00", "2024-02-05 10:00:00", "2024-03-01 10:00:00"},
+		},
+		{
+			name: "ambiguous dates with a 12 hour clock are month first",
+			lines: []string{
+				"1/2/24, 10:00 AM - Alice: hi",
+				"1/2/24, 11:00 AM - Bob: hello",
+			},
+			want: []string{"2024-01-02 10:00:00", "2024-01-02 11:00:00"},
+		},
+		{
+			name: "ambiguous dates with a 24 hour clock are day first",
+			lines: []string{
+				"01.02.24, 10:00 - Alice: hi",
+				"01.02.24, 11:00 - Bob: hello",
+			},
+			want: []string{"2024-02-01 10:00:00", "2024-02-01 11:00:00"},
+		},
+		{
+			name: "year first",
+			lines: []string{
+				"2024-03-01, 10:00 - Alice: hi",
+			},
+			want: []string{"2024-03-01 10:00:00"},
+		},
+		{
+			name: "iOS with seconds and 12 hour clock",
+			lines: []string{
+				"[13/03/2024, 12:05:09 AM] Alice: hi",
+				"[13/03/2024, 12:30:00 PM] Bob: hello",
+			},
+			want: []string{"2024-03-13 00:05:09", "2024-03-13 12:30:00"},
+		},
+		{
+			name: "narrow no-break space before PM",
+			lines: []string{
+				"3/13/24, 9:05\u202fPM - Alice: hi",
+			},
+			want: []string{"2024-03-13 21:05:00"},
+		},
+		{
+			name: "times in the import location",
+			lines: []string{
+				"13/03/2024, 09:05 - Alice: hi",
+				"13/03/2024, 21:30 - Bob: hello",
+			},
+			opts: ImportOptions{
+				Location: time.FixedZone("IST", 5*3600+1800),
+				From:     time.Date(2024, 3, 13, 10, 0, 0, 0, time.FixedZone("IST", 5*3600+1800)),
+			},
+			want: []string{"2024-03-13 16:00:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chat.txt")
+			if err := os.WriteFile(path, []byte(strings.Join(tt.lines, "\n")), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.opts.Location == nil {
+				tt.opts.Location = time.UTC
+			}
+			entries, err := ImportWhatsApp(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Timestamp.UTC().Format("2006-01-02 15:04:05"))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("times = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWhatsAppLine(t *testing.T) {
+	tests := []struct {
+		line string
+		ok   bool
+		ampm byte
+		rest string
+	}{
+		{"13/03/2024, 09:05 - Alice: hi", true, 0, "Alice: hi"},
+		{"[3/13/24, 9:05:00 PM] Alice: hi", true, 'p', "Alice: hi"},
+		{"\u200e[3/13/24, 9:05:00 a.m.] Alice: hi", true, 'a', "Alice: hi"},
+		{"just a continuation line", false, 0, ""},
+		{"see you at 10:00 - ok", false, 0, ""},
+	}
+	for _, tt := range tests {
+		msg := parseWhatsAppLine(tt.line)
+		if (msg != nil) != tt.ok {
+			t.Errorf("parseWhatsAppLine(%q) parsed = %v, want %v", tt.line, msg != nil, tt.ok)
+			continue
+		}
+		if msg != nil && (msg.ampm != tt.ampm || msg.rest != tt.rest) {
+			t.Errorf("parseWhatsAppLine(%q) = ampm %q rest %q, want %q %q", tt.line, msg.ampm, msg.rest, tt.ampm, tt.rest)
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.1
 require (
 	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/signintech/gopdf v0.20.0
+	golang.org/x/text v0.28.0
 )
 
 require github.com/phpdave11/gofpdi v1.0.14-0.20211212211723-1f10f9844311 // indirect
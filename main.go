@@ -1,156 +1,39 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/jung-kurt/gofpdf"
+	"chat-pdf-generator/chatpdf"
 )
 
-// ChatEntry represents a single chat message
-type ChatEntry struct {
-	Timestamp time.Time
-	User      string
-	Message   string
-	R         int
-	G         int
-	B         int
-	IconPath  string
-}
-
-// emojiToImage maps emoji characters to their image paths
-var emojiToImage = map[string]string{
-	"✅": "./images/check.png",
-	"❌": "./images/close.png",
-	"📚": "./images/book.png",
-	"🎯": "./images/target.png",
-	"🚀": "./images/rocket.png",
-}
-
-// PDFGenerator handles PDF document creation and styling
-type PDFGenerator struct {
-	pdf          *gofpdf.Fpdf
-	title        string
-	entries      []ChatEntry
-	margin       float64
-	pageWidth    float64
-	pageHeight   float64
-	logoPath     string
-	headerHeight float64
-	footerHeight float64
-}
-
-// Add a function to check if images exist
-func (g *PDFGenerator) checkImages() {
-	for emoji, path := range emojiToImage {
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			fmt.Printf("Warning: Image file not found: %s for emoji %s\n", path, emoji)
-		}
-	}
-}
-
-// NewPDFGenerator creates a new PDF document with default settings
-func NewPDFGenerator(title string) *PDFGenerator {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.SetAutoPageBreak(true, 20)
-
-	// Set page dimensions (A4 size in mm)
-	pageWidth := 210.0
-	pageHeight := 297.0
-	margin := 20.0
-
-	generator := &PDFGenerator{
-		pdf:          pdf,
-		title:        title,
-		logoPath:     "logo.png",
-		margin:       margin,
-		pageWidth:    pageWidth,
-		pageHeight:   pageHeight,
-		headerHeight: 40.0,
-		footerHeight: 20.0,
-	}
-
-	// Check if images exist
-	generator.checkImages()
-
-	return generator
-}
-
-// AddChatEntry adds a chat entry to the document
-func (g *PDFGenerator) AddChatEntry(entry ChatEntry) {
-	// Store the entry
-	g.entries = append(g.entries, entry)
-}
-
-func (g *PDFGenerator) addHeader() {
-	g.pdf.SetFont("Arial", "B", 24)
-	g.pdf.SetTextColor(0, 0, 0)
-
-	// Add logo if exists
-	if _, err := os.Stat(g.logoPath); err == nil {
-		g.pdf.Image(g.logoPath, g.margin, g.margin, 30, 30, false, "", 0, "")
-	}
-
-	// Add title
-	g.pdf.SetY(g.margin + 10)
-	g.pdf.SetX(g.margin + 35)
-	g.pdf.Cell(0, 20, g.title)
-}
+// Exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
 
-func (g *PDFGenerator) addFooter() {
-	g.pdf.SetY(g.pageHeight - g.footerHeight)
-	g.pdf.SetFont("Arial", "I", 8)
-	g.pdf.SetTextColor(128, 128, 128)
-	g.pdf.Cell(0, 10, fmt.Sprintf("Generated on %s", time.Now().Format("2006-01-02 15:04:05")))
-}
+const usageText = `Usage: chat-pdf-generator [flags] [input]
 
-func (g *PDFGenerator) addText(text string, x, y float64, fontSize float64) {
-	g.pdf.SetFont("Arial", "", fontSize)
-	g.pdf.Text(x, y, text)
-}
+Renders a chat log into a styled PDF document.
 
-// GeneratePDF creates the PDF document
-func (g *PDFGenerator) GeneratePDF(filename string) error {
-	g.pdf.AddPage()
-	g.addHeader()
-
-	contentTop := g.margin + g.headerHeight
-	contentBottom := g.pageHeight - g.footerHeight
-	g.pdf.SetY(contentTop)
-
-	for _, entry := range g.entries {
-		// Add timestamp and user
-		timestamp := entry.Timestamp.Format("2006-01-02 15:04:05")
-		g.pdf.SetFont("Arial", "", 10)
-		g.pdf.SetTextColor(100, 100, 100)
-		g.pdf.Cell(150, 10, timestamp)
-		g.pdf.Cell(100, 10, entry.User)
-
-		// Add message
-		g.pdf.SetY(g.pdf.GetY() + 10)
-		g.pdf.SetFont("Arial", "", 12)
-		g.pdf.SetTextColor(0, 0, 0)
-
-		messageWidth := g.pageWidth - (2 * g.margin)
-		g.pdf.MultiCell(messageWidth, 10, entry.Message, "", "", false)
-		g.pdf.SetY(g.pdf.GetY() + 10)
-
-		// Check if we need a new page
-		if g.pdf.GetY() > contentBottom {
-			g.pdf.AddPage()
-			g.addHeader()
-			g.pdf.SetY(contentTop)
-		}
-	}
+The input is a JSON array or newline-delimited JSON file of messages, or a
+chat export in one of the formats listed under -format; use "-" to read JSON
+from stdin. When no input is given, a built-in sample conversation is
+rendered. Use "-o -" to write the PDF to stdout.
 
-	g.addFooter()
-	return g.pdf.OutputFileAndClose(filename)
-}
+Flags:
+`
 
-func main() {
-	// Create sample chat entries
-	entries := []ChatEntry{
+// sampleEntries returns the built-in demo conversation
+func sampleEntries() []chatpdf.ChatEntry {
+	return []chatpdf.ChatEntry{
 		{
 			Timestamp: time.Now().Add(-2 * time.Hour),
 			User:      "System",
@@ -168,9 +51,163 @@ func main() {
 			B:         0,
 		},
 	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run parses the command line, generates the PDF and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("chat-pdf-generator", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var (
+		input     string
+		output    string
+		title     string
+		logoPath  string
+		pageSize  string
+		theme     string
+		fontPath  string
+		colorMode string
+		noAvatars bool
+		footerURL string
+		backend   string
+		format    string
+		channel   string
+		from      string
+		to        string
+		mediaDir  string
+		preset    string
+		pattern   string
+		layout    string
+		columns   string
+		timezone  string
+		arrange   string
+		self      string
+		threads   string
+		monoFont  string
+		markdown  bool
+		overflow  string
+		numbers   bool
+	)
+	flags.StringVar(&input, "in", "", "input JSON/JSONL `file` (\"-\" for stdin)")
+	flags.StringVar(&input, "i", "", "shorthand for -in")
+	flags.StringVar(&output, "out", "compatibility_report.pdf", "output PDF `file` (\"-\" for stdout)")
+	flags.StringVar(&output, "o", "compatibility_report.pdf", "shorthand for -out")
+	flags.StringVar(&title, "title", "Compatibility Report", "document `title`")
+	flags.StringVar(&logoPath, "logo", "logo.png", "header logo image `file` (empty for none)")
+	flags.StringVar(&pageSize, "page-size", "A4", "page `size`: "+strings.Join(chatpdf.PageSizes(), ", "))
+	flags.StringVar(&theme, "theme", "light", "color `theme`: "+strings.Join(chatpdf.ThemeNames(), ", "))
+	flags.StringVar(&fontPath, "font", chatpdf.DefaultFontPath, "UTF-8 TrueType font `file` (use a CJK font for Chinese, Japanese or Korean text)")
+	flags.StringVar(&monoFont, "mono-font", chatpdf.DefaultMonoFontPath, "UTF-8 TrueType monospace font `file` for code")
+	flags.BoolVar(&markdown, "markdown", false, "format Markdown in messages (Slack, Discord and LLM input is always formatted)")
+	flags.StringVar(&overflow, "code-overflow", "wrap", "what to do with code lines wider than their block (`mode`): "+strings.Join(chatpdf.CodeOverflows(), ", "))
+	flags.BoolVar(&numbers, "line-numbers", false, "number the lines of code blocks")
+	flags.StringVar(&colorMode, "color-mode", "text", "where entry colors are drawn (`mode`): "+strings.Join(chatpdf.ColorModes(), ", "))
+	flags.BoolVar(&noAvatars, "no-avatars", false, "omit the avatar column")
+	flags.StringVar(&arrange, "layout", "classic", "page `layout`: "+strings.Join(chatpdf.Layouts(), ", "))
+	flags.StringVar(&self, "self", "", "`user` whose messages are drawn on the right in the bubbles layout")
+	flags.StringVar(&threads, "threads", "flat", "thread `mode`: flat (replies quote their parent) or nested (replies indented under it)")
+	flags.StringVar(&footerURL, "footer-url", chatpdf.DefaultFooterURL, "`URL` linked from the footer (empty for none)")
+	flags.StringVar(&backend, "backend", "gofpdf", "PDF `library`: "+strings.Join(chatpdf.BackendNames(), ", "))
+	flags.StringVar(&format, "format", "", "input `format`: "+strings.Join(chatpdf.ImportFormats(), ", ")+" (detected when empty)")
+	flags.StringVar(&channel, "channel", "", "`channel` to render from exports with several")
+	flags.StringVar(&from, "from", "", "skip messages before this `date` (YYYY-MM-DD or RFC 3339)")
+	flags.StringVar(&to, "to", "", "skip messages after this `date` (YYYY-MM-DD or RFC 3339)")
+	flags.StringVar(&mediaDir, "media", "", "`directory` of media files referenced by the export (default: next to the input)")
+	flags.StringVar(&preset, "preset", "", "IRC log `client` for line based logs: "+strings.Join(chatpdf.LinePresets(), ", ")+" (detected when empty)")
+	flags.StringVar(&pattern, "pattern", "", "`regexp` for message lines of line based logs, with named groups ts, user and msg")
+	flags.StringVar(&layout, "time-layout", "", "Go time `layout` of the ts group of line based logs or the timestamp column of CSV files")
+	flags.StringVar(&columns, "columns", "", "CSV column `mapping`, such as timestamp=Date,user=2,message=Text (names or 1-based indexes)")
+	flags.StringVar(&timezone, "timezone", "", "time `zone` of times without one, such as Europe/Berlin (default: local time)")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usageText)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		if input != "" {
+			fmt.Fprintln(stderr, "Error: input given both as -in and as an argument")
+			return exitUsage
+		}
+		input = flags.Arg(0)
+	default:
+		fmt.Fprintf(stderr, "Error: unexpected arguments: %s\n", strings.Join(flags.Args()[1:], " "))
+		flags.Usage()
+		return exitUsage
+	}
+
+	var err error
+	importOpts := chatpdf.ImportOptions{
+		Channel:     channel,
+		MediaDir:    mediaDir,
+		Preset:      preset,
+		LinePattern: pattern,
+		TimeLayout:  layout,
+		Location:    time.Local,
+		Warn: func(w chatpdf.Warning) {
+			fmt.Fprintf(stderr, "Warning: %s\n", w)
+		},
+	}
+	if timezone != "" {
+		if importOpts.Location, err = time.LoadLocation(timezone); err != nil {
+			fmt.Fprintf(stderr, "Error: -timezone: %v\n", err)
+			return exitUsage
+		}
+	}
+	if importOpts.Columns, err = parseColumns(columns); err != nil {
+		fmt.Fprintf(stderr, "Error: -columns: %v\n", err)
+		return exitUsage
+	}
+	if importOpts.From, err = parseDate(from, false, importOpts.Location); err != nil {
+		fmt.Fprintf(stderr, "Error: -from: %v\n", err)
+		return exitUsage
+	}
+	if importOpts.To, err = parseDate(to, true, importOpts.Location); err != nil {
+		fmt.Fprintf(stderr, "Error: -to: %v\n", err)
+		return exitUsage
+	}
+
+	generator, err := chatpdf.NewPDFGeneratorWithOptions(chatpdf.Options{
+		Title:           title,
+		LogoPath:        logoPath,
+		PageSize:        pageSize,
+		Theme:           theme,
+		FontPath:        fontPath,
+		MonoFontPath:    monoFont,
+		ColorMode:       chatpdf.ColorMode(colorMode),
+		HideAvatars:     noAvatars,
+		Layout:          chatpdf.Layout(arrange),
+		SelfUser:        self,
+		Threads:         chatpdf.ThreadMode(threads),
+		Markdown:        markdown,
+		CodeOverflow:    chatpdf.CodeOverflow(overflow),
+		CodeLineNumbers: numbers,
+		FooterURL:       footerURL,
+		Backend:         backend,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitUsage
+	}
 
-	// Create PDF generator
-	generator := NewPDFGenerator("Compatibility Report")
+	entries, err := loadInput(input, format, importOpts, stdin)
+	if err != nil {
+		printWarnings(stderr, generator.Warnings())
+		fmt.Fprintf(stderr, "Error loading chat entries: %v\n", err)
+		return exitError
+	}
 
 	// Add chat entries
 	for _, entry := range entries {
@@ -178,11 +215,81 @@ func main() {
 	}
 
 	// Generate the PDF
-	err := generator.GeneratePDF("compatibility_report.pdf")
+	if output == "-" {
+		err = generator.Output(stdout)
+	} else {
+		err = generator.GeneratePDF(output)
+	}
+	printWarnings(stderr, generator.Warnings())
 	if err != nil {
-		fmt.Printf("Error generating PDF: %v\n", err)
-		return
+		fmt.Fprintf(stderr, "Error generating PDF: %v\n", err)
+		return exitError
 	}
 
-	fmt.Println("PDF generated successfully: compatibility_report.pdf")
+	if output != "-" {
+		fmt.Fprintf(stderr, "PDF generated successfully: %s\n", output)
+	}
+	return exitOK
+}
+
+// printWarnings reports problems that did not stop the PDF from being made
+func printWarnings(stderr io.Writer, warnings []chatpdf.Warning) {
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", w)
+	}
+}
+
+// loadInput reads chat entries from path in the given format, JSON from
+// stdin for "-", or the built-in sample conversation when path is empty
+func loadInput(path, format string, opts chatpdf.ImportOptions, stdin io.Reader) ([]chatpdf.ChatEntry, error) {
+	switch path {
+	case "":
+		return sampleEntries(), nil
+	case "-":
+		if format != "" && format != "json" {
+			return nil, fmt.Errorf("only JSON input can be read from stdin")
+		}
+		entries, err := chatpdf.LoadEntriesIn(stdin, opts.Location)
+		if err != nil {
+			return nil, fmt.Errorf("stdin: %w", err)
+		}
+		return opts.Filter(entries), nil
+	default:
+		return chatpdf.Import(format, path, opts)
+	}
+}
+
+// parseDate parses a -from or -to value. A bare date is taken in loc; for
+// the end of a range it covers the whole day.
+func parseDate(s string, end bool, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD or RFC 3339", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// parseColumns parses a -columns value: comma separated field=column pairs
+func parseColumns(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	columns := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(field) == "" || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid mapping %q, want field=column", pair)
+		}
+		columns[strings.TrimSpace(field)] = strings.TrimSpace(column)
+	}
+	return columns, nil
 }